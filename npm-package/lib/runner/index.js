@@ -8,6 +8,9 @@ const { spawn, spawnSync } = require('child_process');
 const ENGINE_DIR = path.resolve(__dirname, '..');
 const PROJECT_DIR = process.cwd();
 const RUNS_DIR = path.join(PROJECT_DIR, '.ralph', 'runs');
+// Dropped by the TUI (or its control socket) to wake a watch-mode wait
+// without editing prd.md.
+const NUDGE_FILE = path.join(PROJECT_DIR, '.ralph', 'nudge');
 
 const DEFAULT_ITERATIONS = 5;
 const RALPH_MAX_TURN_SECONDS = Number.parseInt(process.env.RALPH_MAX_TURN_SECONDS || '900', 10);
@@ -107,6 +110,15 @@ const md5File = (filePath) => {
 
 const fileExists = (filePath) => fs.existsSync(filePath);
 
+const consumeNudge = () => {
+  try {
+    fs.unlinkSync(NUDGE_FILE);
+    return true;
+  } catch {
+    return false;
+  }
+};
+
 const readTail = (filePath, maxLines) => {
   if (!fileExists(filePath)) return '';
   const content = fs.readFileSync(filePath, 'utf8');
@@ -247,6 +259,7 @@ const runWithWatchdog = (logPath, command, args) => new Promise((resolve) => {
       logStream.write('[RALPH] NO OUTPUT: likely waiting for input / hung tool\n');
       if (!killed) {
         killed = true;
+        process.stdout.write('\n[RALPH] NO OUTPUT: likely waiting for input / hung tool\n');
         child.kill('SIGINT');
         setTimeout(() => child.kill('SIGTERM'), 3000);
         setTimeout(() => child.kill('SIGKILL'), 4000);
@@ -256,6 +269,7 @@ const runWithWatchdog = (logPath, command, args) => new Promise((resolve) => {
       logStream.write('[RALPH] TIMEOUT: killing opencode turn\n');
       if (!killed) {
         killed = true;
+        process.stdout.write('\n[RALPH] TIMEOUT: killing opencode turn\n');
         child.kill('SIGINT');
         setTimeout(() => child.kill('SIGTERM'), 3000);
         setTimeout(() => child.kill('SIGKILL'), 4000);
@@ -547,10 +561,13 @@ const runLoop = async () => {
             process.exit(0);
           }
           console.log('⏸️  Project Complete. Waiting for changes in prd.md...');
+          consumeNudge();
+          let nudged = false;
           while (md5File(path.join(PROJECT_DIR, 'prd.md')) === lastHash) {
+            if ((nudged = consumeNudge())) break;
             await new Promise((resolve) => setTimeout(resolve, 2000));
           }
-          console.log('👀 Change detected! Resuming...');
+          console.log(nudged ? '👉 Nudged! Resuming...' : '👀 Change detected! Resuming...');
           i = 1;
           break;
         }
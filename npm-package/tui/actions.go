@@ -0,0 +1,291 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"vibepup-tui/process"
+	"vibepup-tui/tasks"
+	"vibepup-tui/theme"
+	"vibepup-tui/ui"
+)
+
+// Action is something the user can do. Every action lives in the registry
+// below: key presses, the help view and the command palette all read it,
+// so a new feature only needs adding once.
+type Action struct {
+	ID    string
+	Title string
+	// Key returns the action's binding in the keymap, or nil for actions
+	// only reachable from the palette.
+	Key func(*KeyMap) *key.Binding
+	// States lists the screens the action is offered on.
+	States []viewState
+	// When further limits the action, e.g. to while a runner is active.
+	When func(model) bool
+	// Group is the help column the action is listed in; Short also puts it
+	// in the one-line help.
+	Group int
+	Short bool
+	Run   func(*model) tea.Cmd
+}
+
+// Help columns.
+const (
+	groupGeneral = iota
+	groupSearch
+	groupLog
+	groupPanes
+	groupRun
+)
+
+var (
+	onRunning = []viewState{stateRunning}
+	onDone    = []viewState{stateDone}
+	anywhere  = []viewState{stateSetup, stateRunning, stateDone}
+)
+
+func running(m model) bool   { return m.runner != nil }
+func searching(m model) bool { return m.viewport.Searching() }
+
+func notStopping(m model) bool { return running(m) && !m.stopping }
+
+// actions is the registry, in help and palette order. It is filled in by
+// init because the actions refer back to it through the help view.
+var actions []Action
+
+func init() {
+	actions = registry()
+}
+
+func registry() []Action {
+	return []Action{
+		{ID: "help", Title: "Toggle full help", Key: func(k *KeyMap) *key.Binding { return &k.Help }, States: anywhere, Short: true,
+			Run: func(m *model) tea.Cmd { m.help.ShowAll = !m.help.ShowAll; return nil }},
+		{ID: "palette", Title: "Command palette", Key: func(k *KeyMap) *key.Binding { return &k.Palette }, States: anywhere, Short: true,
+			Run: func(m *model) tea.Cmd { return m.openPalette() }},
+		{ID: "quit", Title: "Quit", Key: func(k *KeyMap) *key.Binding { return &k.Quit }, States: []viewState{stateSplash, stateSetup, stateRunning, stateDone}, Short: true,
+			Run: func(m *model) tea.Cmd { return m.quit() }},
+		{ID: "theme.next", Title: "Switch theme", Key: func(k *KeyMap) *key.Binding { return &k.NextTheme }, States: anywhere, Short: true,
+			Run: func(m *model) tea.Cmd { m.nextTheme(); return nil }},
+		{ID: "settings", Title: "Settings", Key: func(k *KeyMap) *key.Binding { return &k.Settings }, States: anywhere, Short: true,
+			Run: func(m *model) tea.Cmd { return m.openSettings() }},
+		{ID: "pet", Title: "Pet the dog", Key: func(k *KeyMap) *key.Binding { return &k.Pet }, States: onRunning,
+			Run: func(m *model) tea.Cmd { return m.petDog() }},
+		{ID: "shell", Title: "Open a shell in the project", Key: func(k *KeyMap) *key.Binding { return &k.Shell }, States: onRunning, Short: true,
+			Run: func(m *model) tea.Cmd { return m.openShell() }},
+		{ID: "prd.edit", Title: "Edit the PRD", States: anywhere,
+			Run: func(m *model) tea.Cmd { return m.editPRD() }},
+		{ID: "task.add", Title: "Add a task to the PRD", Key: func(k *KeyMap) *key.Binding { return &k.AddTask }, States: anywhere,
+			Run: func(m *model) tea.Cmd { return m.openAddTask() }},
+		{ID: "history", Title: "Browse session history", Key: func(k *KeyMap) *key.Binding { return &k.History }, States: []viewState{stateRunning, stateDone}, Short: true,
+			Run: func(m *model) tea.Cmd { return m.openHistory() }},
+
+		{ID: "search", Title: "Search the log", Key: func(k *KeyMap) *key.Binding { return &k.Search }, States: onRunning, Group: groupSearch, Short: true,
+			Run: func(m *model) tea.Cmd { return m.startSearch() }},
+		{ID: "search.next", Title: "Next match", Key: func(k *KeyMap) *key.Binding { return &k.SearchNext }, States: onRunning, When: searching, Group: groupSearch,
+			Run: func(m *model) tea.Cmd { m.viewport.NextMatch(1); return nil }},
+		{ID: "search.prev", Title: "Previous match", Key: func(k *KeyMap) *key.Binding { return &k.SearchPrev }, States: onRunning, When: searching, Group: groupSearch,
+			Run: func(m *model) tea.Cmd { m.viewport.NextMatch(-1); return nil }},
+		{ID: "search.clear", Title: "Clear the search", Key: func(k *KeyMap) *key.Binding { return &k.SearchClear }, States: onRunning, When: searching, Group: groupSearch,
+			Run: func(m *model) tea.Cmd { m.viewport.ClearSearch(); return nil }},
+
+		{ID: "log.live", Title: "Jump to live output", Key: func(k *KeyMap) *key.Binding { return &k.Live }, States: onRunning, Group: groupLog, Short: true,
+			Run: func(m *model) tea.Cmd { m.viewport.GotoBottom(); return nil }},
+		{ID: "log.wrap", Title: "Toggle line wrap", Key: func(k *KeyMap) *key.Binding { return &k.Wrap }, States: onRunning, Group: groupLog,
+			Run: func(m *model) tea.Cmd { m.viewport.Wrap = !m.viewport.Wrap; return nil }},
+		{ID: "log.timestamps", Title: "Toggle timestamps", Key: func(k *KeyMap) *key.Binding { return &k.Timestamps }, States: onRunning, Group: groupLog,
+			Run: func(m *model) tea.Cmd { m.viewport.Timestamps = !m.viewport.Timestamps; return nil }},
+		{ID: "log.fold", Title: "Fold or unfold tool output", Key: func(k *KeyMap) *key.Binding { return &k.Fold }, States: onRunning, Group: groupLog,
+			Run: func(m *model) tea.Cmd { m.viewport.ToggleBlock(); return nil }},
+		{ID: "log.fold_all", Title: "Fold or unfold all tool output", Key: func(k *KeyMap) *key.Binding { return &k.FoldAll }, States: onRunning, Group: groupLog,
+			Run: func(m *model) tea.Cmd { m.viewport.CollapseAll(!m.viewport.AllCollapsed()); return nil }},
+		{ID: "log.links", Title: "Pick a file reference to open", Key: func(k *KeyMap) *key.Binding { return &k.Links }, States: onRunning, Group: groupLog, Short: true,
+			Run: func(m *model) tea.Cmd { m.viewport.ToggleLinkMode(); return nil }},
+
+		{ID: "pane.problems", Title: "Toggle the problems panel", Key: func(k *KeyMap) *key.Binding { return &k.Problems }, States: onRunning, Group: groupPanes, Short: true,
+			Run: func(m *model) tea.Cmd { m.showProblems = !m.showProblems; m.resize(); return nil }},
+		{ID: "pane.activity", Title: "Toggle the activity pane", Key: func(k *KeyMap) *key.Binding { return &k.Activity }, States: onRunning, Group: groupPanes,
+			Run: func(m *model) tea.Cmd { m.toggleActivity(); return nil }},
+		{ID: "pane.next", Title: "Focus the next pane", Key: func(k *KeyMap) *key.Binding { return &k.FocusNext }, States: onRunning, Group: groupPanes,
+			Run: func(m *model) tea.Cmd { m.cycleFocus(1); return nil }},
+		{ID: "pane.prev", Title: "Focus the previous pane", Key: func(k *KeyMap) *key.Binding { return &k.FocusPrev }, States: onRunning, Group: groupPanes,
+			Run: func(m *model) tea.Cmd { m.cycleFocus(-1); return nil }},
+		{ID: "layout.next", Title: "Next layout preset", Key: func(k *KeyMap) *key.Binding { return &k.Preset }, States: onRunning, Group: groupPanes,
+			Run: func(m *model) tea.Cmd { return m.switchPreset() }},
+		{ID: "layout.grow", Title: "Grow the focused pane", Key: func(k *KeyMap) *key.Binding { return &k.Grow }, States: onRunning, Group: groupPanes,
+			Run: func(m *model) tea.Cmd { return m.resizeSplit(1) }},
+		{ID: "layout.shrink", Title: "Shrink the focused pane", Key: func(k *KeyMap) *key.Binding { return &k.Shrink }, States: onRunning, Group: groupPanes,
+			Run: func(m *model) tea.Cmd { return m.resizeSplit(-1) }},
+
+		{ID: "run.pause", Title: "Pause the runner", Key: func(k *KeyMap) *key.Binding { return &k.Pause }, States: onRunning, Group: groupRun,
+			When: func(m model) bool { return running(m) && !m.runner.Paused() },
+			Run:  func(m *model) tea.Cmd { m.pause(); return nil }},
+		{ID: "run.resume", Title: "Resume the runner", Key: func(k *KeyMap) *key.Binding { return &k.Pause }, States: onRunning, Group: groupRun,
+			When: func(m model) bool { return running(m) && m.runner.Paused() },
+			Run:  func(m *model) tea.Cmd { m.resume(); return nil }},
+		{ID: "run.nudge", Title: "Nudge the waiting runner", Key: func(k *KeyMap) *key.Binding { return &k.Nudge }, States: onRunning, Group: groupRun,
+			When: func(m model) bool { return running(m) && m.waiting },
+			Run:  func(m *model) tea.Cmd { m.nudge(); return nil }},
+		{ID: "run.stop", Title: "Stop the runner", States: onRunning, When: notStopping, Group: groupRun,
+			Run: func(m *model) tea.Cmd { m.stop(); return nil }},
+		{ID: "run.finish", Title: "Stop after this iteration", States: onRunning, Group: groupRun,
+			When: func(m model) bool { return notStopping(m) && !m.stopAtLoop },
+			Run:  func(m *model) tea.Cmd { m.stopAfterIteration(); return nil }},
+		{ID: "run.detach", Title: "Detach and leave the runner going", States: onRunning, When: running, Group: groupRun,
+			Run: func(m *model) tea.Cmd { return m.detach() }},
+		{ID: "run.start", Title: "Run again with the same arguments", Key: func(k *KeyMap) *key.Binding { return &k.Rerun }, States: onDone, Group: groupRun, Short: true,
+			Run: func(m *model) tea.Cmd { return m.rerun() }},
+		{ID: "run.watch", Title: "Restart in watch mode", Key: func(k *KeyMap) *key.Binding { return &k.Watch }, States: onDone, Group: groupRun, Short: true,
+			Run: func(m *model) tea.Cmd { m.selected, m.args = "watch", m.cfg.Args; return m.rerun() }},
+	}
+}
+
+// keyState is the screen whose actions apply, counting the new-project
+// prompt as setup.
+func (m model) keyState() viewState {
+	if m.askingIdea() {
+		return stateSetup
+	}
+	return m.state
+}
+
+// keys is the keymap of the current screen.
+func (m model) keys() *KeyMap {
+	return m.keymaps.For(m.keyState())
+}
+
+// askingIdea reports whether the new-project prompt is open.
+func (m model) askingIdea() bool {
+	return m.state == stateRunning && m.runner == nil && m.selected == "new" && m.newForm.State != huh.StateCompleted
+}
+
+// available reports whether a can be used right now.
+func (m model) available(a Action) bool {
+	if !slices.Contains(a.States, m.keyState()) {
+		return false
+	}
+	return a.When == nil || a.When(m)
+}
+
+// actionFor finds the available action bound to msg.
+func (m model) actionFor(msg tea.KeyMsg) (Action, bool) {
+	// Printable keys belong to the new-project prompt while it's open.
+	if m.askingIdea() && msg.Type == tea.KeyRunes {
+		return Action{}, false
+	}
+	for _, a := range actions {
+		if a.Key == nil || !m.available(a) {
+			continue
+		}
+		if key.Matches(msg, *a.Key(m.keys())) {
+			return a, true
+		}
+	}
+	return Action{}, false
+}
+
+// helpKeys is the help view's keymap: the bindings of the actions
+// available right now.
+type helpKeys struct {
+	short []key.Binding
+	full  [][]key.Binding
+}
+
+func (h helpKeys) ShortHelp() []key.Binding  { return h.short }
+func (h helpKeys) FullHelp() [][]key.Binding { return h.full }
+
+func (m model) helpKeys() helpKeys {
+	var h helpKeys
+	columns := map[int][]key.Binding{}
+	seen := map[*key.Binding]bool{}
+	for _, a := range actions {
+		if a.Key == nil || !m.available(a) {
+			continue
+		}
+		b := a.Key(m.keys())
+		if seen[b] {
+			continue
+		}
+		seen[b] = true
+		if a.Short {
+			h.short = append(h.short, *b)
+		}
+		columns[a.Group] = append(columns[a.Group], *b)
+	}
+	for g := groupGeneral; g <= groupRun; g++ {
+		if len(columns[g]) > 0 {
+			h.full = append(h.full, columns[g])
+		}
+	}
+	return h
+}
+
+// nextTheme cycles through the themes for this session.
+func (m *model) nextTheme() {
+	names := theme.Names()
+	i := slices.Index(names, m.cfg.Theme)
+	m.cfg.Theme = names[(i+1)%len(names)]
+	m.applyLiveSettings()
+}
+
+func (m *model) petDog() tea.Cmd {
+	m.dogState = "happy"
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return "dog_reset"
+	})
+}
+
+func (m *model) toggleActivity() {
+	m.showActivity = !m.showActivity
+	if !m.panes().Has(m.focus) {
+		m.focus = ui.PaneLog
+	}
+	m.resize()
+}
+
+// editPRD opens the project's prd.md in $EDITOR.
+func (m *model) editPRD() tea.Cmd {
+	path := filepath.Join(m.projectDir, tasks.File)
+	return tea.ExecProcess(process.EditorCmd(path, 1, 1), func(err error) tea.Msg {
+		return process.EditorDoneMsg{Path: path, Err: err}
+	})
+}
+
+// pause stops the runner's process group until resumed.
+func (m *model) pause() {
+	if err := m.runner.Pause(); err != nil {
+		m.viewport.WriteNote("Pause failed: " + err.Error())
+		return
+	}
+	m.viewport.WriteNote("--- Runner paused ---")
+}
+
+// nudge wakes a watch-mode runner that's waiting for the PRD to change,
+// without changing it.
+func (m *model) nudge() {
+	path := filepath.Join(m.projectDir, ".ralph", "nudge")
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err == nil {
+		err = os.WriteFile(path, nil, 0o644)
+	}
+	if err != nil {
+		m.viewport.WriteNote("Nudge failed: " + err.Error())
+		return
+	}
+	m.viewport.WriteNote("--- Nudged the runner ---")
+}
+
+func (m *model) resume() {
+	if err := m.runner.Resume(); err != nil {
+		m.viewport.WriteNote("Resume failed: " + err.Error())
+		return
+	}
+	m.viewport.WriteNote("--- Runner resumed ---")
+}
@@ -0,0 +1,178 @@
+package activity
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Category groups tools by what a call says about the turn.
+type Category string
+
+const (
+	Explore Category = "explore"
+	Edit    Category = "edit"
+	Run     Category = "run"
+	Other   Category = "other"
+)
+
+// Categorize maps an opencode tool name to its category.
+func Categorize(tool string) Category {
+	switch strings.ToLower(tool) {
+	case "read", "grep", "glob", "list", "ls", "ast_grep_search", "webfetch", "websearch", "codesearch", "lsp_hover", "lsp_diagnostics":
+		return Explore
+	case "write", "edit", "multiedit", "patch", "ast_grep_replace":
+		return Edit
+	case "bash":
+		return Run
+	}
+	return Other
+}
+
+// Call is one tool invocation. A call lasts until the next one starts, so
+// its duration covers the tool run and the agent's thinking after it.
+type Call struct {
+	Tool      string
+	Title     string
+	Iteration int
+	Start     time.Time
+	End       time.Time // zero while the call is the latest one
+}
+
+// Duration returns how long the call took, or has taken so far.
+func (c Call) Duration(now time.Time) time.Duration {
+	if c.End.IsZero() {
+		return now.Sub(c.Start)
+	}
+	return c.End.Sub(c.Start)
+}
+
+// Mode sums up what the agent is doing in the current iteration.
+type Mode string
+
+const (
+	Idle      Mode = "idle"
+	Exploring Mode = "exploring"
+	Editing   Mode = "editing"
+	Running   Mode = "running"
+	// Thrashing means the agent keeps repeating the same call.
+	Thrashing Mode = "thrashing"
+)
+
+const (
+	// maxCalls bounds the history kept for the panel.
+	maxCalls = 500
+	// window is how many recent calls decide the mode.
+	window = 6
+	// thrashRepeats is how often an identical call has to recur within an
+	// iteration to count as thrashing.
+	thrashRepeats = 3
+)
+
+// Count is the number of calls to one tool.
+type Count struct {
+	Tool string
+	N    int
+}
+
+// Feed records tool calls as they appear in the runner output.
+type Feed struct {
+	calls     []Call
+	iteration int
+	counts    map[string]int
+	repeats   map[string]int
+	thrashing bool
+}
+
+func NewFeed() *Feed {
+	return &Feed{counts: map[string]int{}, repeats: map[string]int{}}
+}
+
+// BeginIteration marks the start of a new runner loop. Counts restart.
+func (f *Feed) BeginIteration(n int, at time.Time) {
+	f.Finish(at)
+	f.iteration = n
+	f.counts = map[string]int{}
+	f.repeats = map[string]int{}
+	f.thrashing = false
+}
+
+// Iteration returns the loop calls are currently attributed to.
+func (f *Feed) Iteration() int {
+	return f.iteration
+}
+
+// Add records a call starting at at, ending the previous one.
+func (f *Feed) Add(tool, title string, at time.Time) {
+	f.Finish(at)
+	f.calls = append(f.calls, Call{Tool: tool, Title: title, Iteration: f.iteration, Start: at})
+	if len(f.calls) > maxCalls {
+		f.calls = append(f.calls[:0], f.calls[len(f.calls)-maxCalls:]...)
+	}
+	f.counts[tool]++
+	k := tool + "\x00" + title
+	f.repeats[k]++
+	if f.repeats[k] >= thrashRepeats {
+		f.thrashing = true
+	}
+}
+
+// Finish ends the running call, if any, at at.
+func (f *Feed) Finish(at time.Time) {
+	if n := len(f.calls); n > 0 && f.calls[n-1].End.IsZero() {
+		f.calls[n-1].End = at
+	}
+}
+
+// Len returns the number of calls kept.
+func (f *Feed) Len() int {
+	return len(f.calls)
+}
+
+// Recent returns up to n calls, newest first.
+func (f *Feed) Recent(n int) []Call {
+	n = min(n, len(f.calls))
+	out := make([]Call, 0, n)
+	for i := len(f.calls) - 1; i >= len(f.calls)-n; i-- {
+		out = append(out, f.calls[i])
+	}
+	return out
+}
+
+// Counts returns this iteration's calls per tool, most used first.
+func (f *Feed) Counts() []Count {
+	out := make([]Count, 0, len(f.counts))
+	for tool, n := range f.counts {
+		out = append(out, Count{tool, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].N != out[j].N {
+			return out[i].N > out[j].N
+		}
+		return out[i].Tool < out[j].Tool
+	})
+	return out
+}
+
+// Mode classifies the current iteration from its most recent calls.
+func (f *Feed) Mode() Mode {
+	if f.thrashing {
+		return Thrashing
+	}
+	byCategory := map[Category]int{}
+	seen := 0
+	for i := len(f.calls) - 1; i >= 0 && seen < window && f.calls[i].Iteration == f.iteration; i-- {
+		byCategory[Categorize(f.calls[i].Tool)]++
+		seen++
+	}
+	switch {
+	case seen == 0:
+		return Idle
+	case byCategory[Edit] > 0:
+		return Editing
+	case byCategory[Explore]*2 >= seen:
+		return Exploring
+	default:
+		return Running
+	}
+}
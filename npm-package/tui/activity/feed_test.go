@@ -0,0 +1,45 @@
+package activity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeedTracksCallsAndMode(t *testing.T) {
+	t0 := time.Date(2026, 1, 26, 13, 0, 0, 0, time.UTC)
+	f := NewFeed()
+	f.BeginIteration(1, t0)
+	f.Add("Glob", `{"pattern":"**/*.go"}`, t0)
+	f.Add("Read", "main.go", t0.Add(4*time.Second))
+	f.Add("Bash", "Run tests", t0.Add(10*time.Second))
+
+	if got := f.Mode(); got != Exploring {
+		t.Errorf("Mode = %s, want exploring", got)
+	}
+	calls := f.Recent(3)
+	if calls[0].Tool != "Bash" || !calls[0].End.IsZero() {
+		t.Errorf("newest call should be the running Bash, got %+v", calls[0])
+	}
+	if d := calls[1].Duration(t0); d != 6*time.Second {
+		t.Errorf("Read lasted %s, want 6s", d)
+	}
+
+	f.Add("Edit", "main.go", t0.Add(20*time.Second))
+	if got := f.Mode(); got != Editing {
+		t.Errorf("Mode = %s, want editing", got)
+	}
+
+	f.BeginIteration(2, t0.Add(time.Minute))
+	if got := f.Mode(); got != Idle || len(f.Counts()) != 0 {
+		t.Errorf("new iteration should reset, got %s %v", got, f.Counts())
+	}
+	for range thrashRepeats {
+		f.Add("Bash", "npm test", t0.Add(2*time.Minute))
+	}
+	if got := f.Mode(); got != Thrashing {
+		t.Errorf("Mode = %s, want thrashing", got)
+	}
+	if c := f.Counts(); len(c) != 1 || c[0] != (Count{"Bash", thrashRepeats}) {
+		t.Errorf("Counts = %v", c)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"vibepup-tui/tasks"
+)
+
+// openAddTask asks for a task to append to the PRD.
+func (m *model) openAddTask() tea.Cmd {
+	keymap := huh.NewDefaultKeyMap()
+	keymap.Quit = key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "back"))
+	m.taskText = new(string)
+	m.taskForm = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Add a task").
+				Description("Appended to " + tasks.File + " as an unchecked item").
+				Prompt("- [ ] ").
+				Value(m.taskText),
+		),
+	).WithTheme(huh.ThemeDracula()).WithKeyMap(keymap)
+	m.prevState = m.state
+	m.state = stateAddTask
+	return m.taskForm.Init()
+}
+
+func (m model) updateAddTask(msg tea.Msg) (model, tea.Cmd) {
+	form, cmd := m.taskForm.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.taskForm = f
+	}
+	switch m.taskForm.State {
+	case huh.StateCompleted:
+		m.state = m.prevState
+		if err := m.appendTask(*m.taskText); err != nil {
+			m.notice = "Couldn't add the task: " + err.Error()
+			if m.state == stateRunning {
+				m.viewport.WriteNote(m.notice)
+			}
+		}
+	case huh.StateAborted:
+		m.state = m.prevState
+	}
+	return m, cmd
+}
+
+// appendTask adds an unchecked task to the end of the PRD, where the
+// runner will get to it after the ones already there.
+func (m *model) appendTask(text string) error {
+	if err := tasks.Append(m.projectDir, text); err != nil {
+		return err
+	}
+	m.reloadTasks()
+	if m.state == stateRunning {
+		m.viewport.WriteNote("--- Added a task: " + strings.TrimSpace(text) + " ---")
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+
+	"vibepup-tui/config"
+	"vibepup-tui/events"
+	"vibepup-tui/notify"
+	"vibepup-tui/summary"
+	"vibepup-tui/tasks"
+	"vibepup-tui/webhook"
+)
+
+// alerts turns a run into notifications and webhook deliveries.
+type alerts struct {
+	watcher  *notify.Watcher
+	notifier *notify.Notifier
+	hooks    *webhook.Sender // nil without webhook URLs
+}
+
+// newAlerts watches a run in dir whose PRD starts out as list, notifying
+// and posting to webhooks as cfg says. The bell and OSC sequences go to
+// stderr, and only when it's a terminal, so they never end up in a log
+// file or --json output.
+func newAlerts(dir string, list tasks.List, cfg config.Config) alerts {
+	n := &notify.Notifier{
+		Routes: map[notify.Kind][]notify.Channel{},
+		Every:  time.Duration(cfg.Notify.MinIntervalSeconds) * time.Second,
+		Tmux:   os.Getenv("TMUX") != "",
+	}
+	for kind, spec := range map[notify.Kind]string{
+		notify.TaskDone:  cfg.Notify.TaskDone,
+		notify.AllDone:   cfg.Notify.AllDone,
+		notify.Exhausted: cfg.Notify.Exhausted,
+		notify.Watchdog:  cfg.Notify.Watchdog,
+		notify.Prompt:    cfg.Notify.Prompt,
+	} {
+		// The config has been validated already.
+		n.Routes[kind], _ = notify.ParseChannels(spec)
+	}
+	if isatty.IsTerminal(os.Stderr.Fd()) {
+		n.Term = os.Stderr
+	}
+	n.Desktop = notify.NotifySendCommand()
+	a := alerts{watcher: notify.NewWatcher(dir, list), notifier: n}
+
+	var urls []string
+	for _, u := range strings.Split(cfg.Webhook.URLs, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) > 0 {
+		a.hooks = &webhook.Sender{URLs: urls, Secret: cfg.Webhook.Secret, Retries: cfg.Webhook.Retries,
+			Backoff: time.Second, Spool: webhook.SpoolPath(dir)}
+		for _, k := range strings.Split(cfg.Webhook.Events, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				a.hooks.Events = append(a.hooks.Events, notify.Kind(k))
+			}
+		}
+		a.hooks.Start()
+	}
+	return a
+}
+
+// send passes events on to the notifier and the webhooks.
+func (a alerts) send(evs []notify.Event) {
+	a.notifier.Send(evs...)
+	if a.hooks != nil {
+		a.hooks.Send(evs...)
+	}
+}
+
+// started reports the runner starting.
+func (a alerts) started(at time.Time) {
+	a.send([]notify.Event{a.watcher.Start(at)})
+}
+
+// finished reports the runner exiting with outcome.
+func (a alerts) finished(outcome string) {
+	a.send([]notify.Event{a.watcher.Finish(outcome)})
+}
+
+// close gives the webhooks a moment to deliver what's queued; the rest is
+// spooled for the next run.
+func (a alerts) close() {
+	if a.hooks != nil {
+		a.hooks.Close(3 * time.Second)
+	}
+}
+
+// line notifies of what an output line brings.
+func (a alerts) line(line string, ev events.Event, ok bool) {
+	a.send(a.watcher.Line(line, ev, ok))
+}
+
+// catchUp follows a line without notifying, for output from before the
+// TUI was there to see it.
+func (a alerts) catchUp(line string, ev events.Event, ok bool) {
+	a.watcher.Line(line, ev, ok)
+}
+
+// tasks notifies of tasks checked off in the PRD, now list.
+func (a alerts) tasks(list tasks.List) {
+	a.send(a.watcher.Tasks(list))
+}
+
+// touchesTasks reports whether ev may have checked tasks off: a new loop,
+// or a tool working on the PRD.
+func touchesTasks(ev events.Event) bool {
+	return ev.Kind == events.Loop || ev.Kind == events.Tool && strings.Contains(ev.Title, tasks.File)
+}
+
+// alertFeed alerts on a run without a model to follow it, as under a
+// supervisor: it parses the lines itself and reloads the PRD as they say.
+type alertFeed struct {
+	alerts
+	dir  string
+	list tasks.List
+	run  summary.Run
+}
+
+func (f *alertFeed) line(line string) {
+	ev, ok := events.Parse(line)
+	if ok {
+		f.run.Feed(ev)
+	}
+	f.alerts.line(line, ev, ok)
+	if ok && touchesTasks(ev) {
+		f.reload()
+	}
+}
+
+func (f *alertFeed) exit(err error) {
+	f.run.Finish(err, time.Now())
+	f.reload()
+	f.finished(f.run.Outcome())
+}
+
+func (f *alertFeed) reload() {
+	if list, err := tasks.Load(f.dir); err == nil {
+		f.list = list
+		f.tasks(list)
+	}
+}
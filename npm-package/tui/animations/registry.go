@@ -1,6 +1,9 @@
 package animations
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type Kind string
 
@@ -37,6 +40,22 @@ func Get(name string) Preset {
 	return presets["vhs-scan"]
 }
 
+// Lookup returns the named preset and whether it is registered.
+func Lookup(name string) (Preset, bool) {
+	p, ok := presets[name]
+	return p, ok
+}
+
+// Names returns the registered preset names in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func All() []Preset {
 	out := make([]Preset, 0, len(presets))
 	for _, p := range presets {
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Config is the effective TUI configuration after all layers are merged.
+type Config struct {
+	Quiet    bool
+	NoEmoji  bool
+	Dense    bool
+	PerfLow  bool
+	Snark    string
+	Theme    string
+	Anim     string
+	FX       string
+	NoAlt    bool
+	ForceRun bool
+	Runner   string
+	Design   bool
+	Watchdog Watchdog
+	Log      Log
+	Layout   Layout
+	Notify   Notify
+	Webhook  Webhook
+	// Keys remaps key bindings, keyed by action ID such as "quit" or, for
+	// one screen only, "running.quit". It is read from [keys] tables in the
+	// config files; the TUI validates the IDs and key names.
+	Keys map[string][]string
+
+	// Args holds the positional arguments forwarded to the runner.
+	Args []string
+	// PrintConfig asks main to print the effective config and exit.
+	PrintConfig bool
+	// StatusLine asks main to print the project's status as one line and
+	// exit.
+	StatusLine bool
+	// JSON asks main to run the runner headless, printing JSON events.
+	JSON bool
+
+	sources map[string]Source
+}
+
+// Watchdog holds the runner's per-turn limits, passed to it as
+// RALPH_MAX_TURN_SECONDS and RALPH_NO_OUTPUT_SECONDS.
+type Watchdog struct {
+	MaxTurnSeconds  int
+	NoOutputSeconds int
+}
+
+// Log controls the log viewport's memory use.
+type Log struct {
+	// Scrollback is how many lines are kept in memory; older lines are
+	// read back from the session file on disk.
+	Scrollback int
+	// ThemeColors remaps the basic ANSI colours in runner output to the
+	// active theme.
+	ThemeColors bool
+	// Wrap soft-wraps long lines instead of cutting them at the edge.
+	Wrap bool
+	// Timestamps shows when each line arrived.
+	Timestamps bool
+	// CollapseTools starts opencode tool output folded under its header.
+	CollapseTools bool
+}
+
+// Layout picks the pane arrangement of the running screen.
+type Layout struct {
+	// Preset names the starting arrangement; L cycles through them.
+	Preset string
+	// Side and Bottom override the preset's panes, as comma-separated lists.
+	Side   string
+	Bottom string
+	// SideWidth (percent) and BottomHeight (rows) override the split
+	// sizes; zero keeps the preset's.
+	SideWidth    int
+	BottomHeight int
+}
+
+// Notify picks the notifications for each kind of run event. Each is a
+// comma-separated list of channels (bell, osc9, osc777, notify-send), or
+// empty for none.
+type Notify struct {
+	TaskDone  string
+	AllDone   string
+	Exhausted string
+	Watchdog  string
+	Prompt    string
+	// MinIntervalSeconds is the least time between two notifications of
+	// one kind.
+	MinIntervalSeconds int
+}
+
+// Webhook posts run events as JSON to URLs.
+type Webhook struct {
+	// URLs is a comma-separated list; empty turns webhooks off.
+	URLs string
+	// Events is a comma-separated list of the event types to post.
+	Events string
+	// Secret, if set, signs each body with HMAC-SHA256.
+	Secret string
+	// Retries is how many times a failed delivery is retried before it is
+	// spooled.
+	Retries int
+}
+
+// Source names the layer a setting was taken from.
+type Source string
+
+const SourceDefault Source = "default"
+
+// Source reports where the value for key came from.
+func (c Config) Source(key string) Source {
+	if s, ok := c.sources[key]; ok {
+		return s
+	}
+	return SourceDefault
+}
+
+// field describes one setting. The same key is used in TOML files, as the
+// VIBEPUP_* environment variable and as the command-line flag, so adding a
+// setting here makes it available in every layer.
+type field struct {
+	key   string
+	usage string
+	ptr   func(*Config) any
+	check func(v any) error
+	// secret values aren't shown by --print-config.
+	secret bool
+}
+
+var fields = []field{
+	{key: "theme", usage: "theme name", ptr: func(c *Config) any { return &c.Theme }, check: checkTheme},
+	{key: "snark", usage: "snark level: mild|spicy|unhinged", ptr: func(c *Config) any { return &c.Snark }, check: checkSnark},
+	{key: "anim", usage: "animation preset", ptr: func(c *Config) any { return &c.Anim }, check: checkAnim},
+	{key: "fx", usage: "sysc effect: fire|matrix|none", ptr: func(c *Config) any { return &c.FX }, check: checkFX},
+	{key: "quiet", usage: "reduce motion and chatter", ptr: func(c *Config) any { return &c.Quiet }},
+	{key: "no_emoji", usage: "disable emoji rendering", ptr: func(c *Config) any { return &c.NoEmoji }},
+	{key: "dense", usage: "increase animation density", ptr: func(c *Config) any { return &c.Dense }},
+	{key: "perf_low", usage: "lower FPS and effects for slower terminals", ptr: func(c *Config) any { return &c.PerfLow }},
+	{key: "no_alt", usage: "run inline instead of full screen: a compact view at the bottom of the terminal, with finished iterations left in its scrollback", ptr: func(c *Config) any { return &c.NoAlt }},
+	{key: "force_run", usage: "show the TUI even if stdout is not a TTY, instead of plain lines", ptr: func(c *Config) any { return &c.ForceRun }},
+	{key: "runner", usage: "path to runner script (internal use)", ptr: func(c *Config) any { return &c.Runner }},
+	{key: "design", usage: "inject the frontend-design skill into every turn", ptr: func(c *Config) any { return &c.Design }},
+	{key: "watchdog.max_turn_seconds", usage: "kill an agent turn after this many seconds", ptr: func(c *Config) any { return &c.Watchdog.MaxTurnSeconds }, check: checkPositive},
+	{key: "watchdog.no_output_seconds", usage: "kill an agent turn after this many silent seconds", ptr: func(c *Config) any { return &c.Watchdog.NoOutputSeconds }, check: checkPositive},
+	{key: "log.scrollback", usage: "log lines kept in memory; older lines are read from the session file", ptr: func(c *Config) any { return &c.Log.Scrollback }, check: checkPositive},
+	{key: "log.theme_colors", usage: "remap the colours in runner output to the theme", ptr: func(c *Config) any { return &c.Log.ThemeColors }},
+	{key: "log.wrap", usage: "soft-wrap long log lines (toggle with w)", ptr: func(c *Config) any { return &c.Log.Wrap }},
+	{key: "log.timestamps", usage: "show a timestamp gutter in the log (toggle with T)", ptr: func(c *Config) any { return &c.Log.Timestamps }},
+	{key: "log.collapse_tools", usage: "fold tool-call output under its header (toggle all with Z)", ptr: func(c *Config) any { return &c.Log.CollapseTools }},
+	{key: "layout.preset", usage: "pane arrangement: focus-log|dashboard|zen (cycle with L)", ptr: func(c *Config) any { return &c.Layout.Preset }, check: checkPreset},
+	{key: "layout.side", usage: "panes in the side column, e.g. status,tasks", ptr: func(c *Config) any { return &c.Layout.Side }, check: checkPanes},
+	{key: "layout.bottom", usage: "panes in the bottom row, e.g. activity", ptr: func(c *Config) any { return &c.Layout.Bottom }, check: checkPanes},
+	{key: "layout.side_width", usage: "side column width in percent, 0 for the preset's (resize with < and >)", ptr: func(c *Config) any { return &c.Layout.SideWidth }, check: checkNonNegative},
+	{key: "layout.bottom_height", usage: "bottom row height in rows, 0 for the preset's", ptr: func(c *Config) any { return &c.Layout.BottomHeight }, check: checkNonNegative},
+	{key: "notify.task_done", usage: "notify when a task is checked off: bell,osc9,osc777,notify-send or none", ptr: func(c *Config) any { return &c.Notify.TaskDone }, check: checkChannels},
+	{key: "notify.all_done", usage: "notify when every task is done", ptr: func(c *Config) any { return &c.Notify.AllDone }, check: checkChannels},
+	{key: "notify.exhausted", usage: "notify when every model fails an iteration", ptr: func(c *Config) any { return &c.Notify.Exhausted }, check: checkChannels},
+	{key: "notify.watchdog", usage: "notify when the watchdog kills a turn", ptr: func(c *Config) any { return &c.Notify.Watchdog }, check: checkChannels},
+	{key: "notify.prompt", usage: "notify when the agent seems to wait for input", ptr: func(c *Config) any { return &c.Notify.Prompt }, check: checkChannels},
+	{key: "notify.min_interval_seconds", usage: "least time between two notifications of one kind", ptr: func(c *Config) any { return &c.Notify.MinIntervalSeconds }, check: checkNonNegative},
+	{key: "webhook.urls", usage: "comma-separated URLs to POST run events to as JSON", ptr: func(c *Config) any { return &c.Webhook.URLs }, check: checkURLs},
+	{key: "webhook.events", usage: "event types to post: started,iteration,task_done,all_done,exhausted,watchdog,prompt,finished", ptr: func(c *Config) any { return &c.Webhook.Events }, check: checkEvents},
+	{key: "webhook.secret", usage: "sign webhook bodies with HMAC-SHA256 in X-Vibepup-Signature", ptr: func(c *Config) any { return &c.Webhook.Secret }, secret: true},
+	{key: "webhook.retries", usage: "times a failed webhook delivery is retried before it's spooled", ptr: func(c *Config) any { return &c.Webhook.Retries }, check: checkNonNegative},
+}
+
+// Default returns the built-in configuration.
+func Default() Config {
+	return Config{
+		Snark: "mild",
+		Theme: "dracula-vibe",
+		Anim:  "vhs-scan",
+		FX:    "fire",
+		Watchdog: Watchdog{
+			MaxTurnSeconds:  900,
+			NoOutputSeconds: 180,
+		},
+		Log:    Log{Scrollback: 5000, CollapseTools: true},
+		Layout: Layout{Preset: "focus-log"},
+		Notify: Notify{
+			TaskDone:           "osc9,notify-send",
+			AllDone:            "bell,osc9,notify-send",
+			Exhausted:          "bell,osc9,notify-send",
+			Watchdog:           "osc9,notify-send",
+			Prompt:             "bell,osc9,notify-send",
+			MinIntervalSeconds: 60,
+		},
+		Webhook: Webhook{
+			Events:  "started,task_done,all_done,exhausted,watchdog,prompt,finished",
+			Retries: 3,
+		},
+	}
+}
+
+func lookupField(key string) (field, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+func fieldKeys() []string {
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		keys[i] = f.key
+	}
+	return keys
+}
+
+// envName maps a key such as "no_emoji" or "log.scrollback" to VIBEPUP_NO_EMOJI
+// or VIBEPUP_LOG_SCROLLBACK.
+func envName(key string) string {
+	return "VIBEPUP_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// flagName maps a key to its command-line spelling, e.g. no-emoji.
+func flagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
+
+// setString parses a textual value (from env or flags) into the field.
+func (c *Config) setString(f field, raw string, src Source) error {
+	switch p := f.ptr(c).(type) {
+	case *string:
+		*p = raw
+	case *bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a boolean (%s)", f.key, raw, src)
+		}
+		*p = v
+	case *int:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a whole number (%s)", f.key, raw, src)
+		}
+		*p = v
+	}
+	c.setSource(f.key, src)
+	return nil
+}
+
+// setValue stores a decoded TOML value into the field.
+func (c *Config) setValue(f field, v any, src Source) error {
+	switch p := f.ptr(c).(type) {
+	case *string:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string, got %v (%s)", f.key, v, src)
+		}
+		*p = s
+	case *bool:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%s: expected true or false, got %v (%s)", f.key, v, src)
+		}
+		*p = b
+	case *int:
+		n, ok := v.(int64)
+		if !ok {
+			return fmt.Errorf("%s: expected a whole number, got %v (%s)", f.key, v, src)
+		}
+		*p = int(n)
+	}
+	c.setSource(f.key, src)
+	return nil
+}
+
+func (c *Config) setSource(key string, src Source) {
+	if c.sources == nil {
+		c.sources = map[string]Source{}
+	}
+	c.sources[key] = src
+}
+
+// value formats a field's current value for display.
+func (c Config) value(f field) string {
+	switch p := f.ptr(&c).(type) {
+	case *string:
+		if f.secret && *p != "" {
+			return `"(set)"`
+		}
+		return strconv.Quote(*p)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *int:
+		return strconv.Itoa(*p)
+	}
+	return ""
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayersInPrecedenceOrder(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("VIBEPUP_SNARK", "unhinged")
+
+	writeFile(t, filepath.Join(home, "vibepup", "config.toml"), "theme = \"mono-chill\"\nsnark = \"spicy\"\ndense = true\n")
+	writeFile(t, filepath.Join(project, ProjectFile), "# project overrides\ntheme = \"halloween-glitch\"\n")
+
+	c, err := Load(project, []string{"--fx", "none", "10"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	checks := []struct {
+		key, got, want string
+		src            string
+	}{
+		{"theme", c.Theme, "halloween-glitch", "project"},
+		{"snark", c.Snark, "unhinged", "env VIBEPUP_SNARK"},
+		{"fx", c.FX, "none", "flag --fx"},
+		{"anim", c.Anim, "vhs-scan", "default"},
+	}
+	for _, tc := range checks {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.key, tc.got, tc.want)
+		}
+		if src := string(c.Source(tc.key)); !strings.HasPrefix(src, tc.src) {
+			t.Errorf("%s source = %q, want prefix %q", tc.key, src, tc.src)
+		}
+	}
+	if !c.Dense || !strings.HasPrefix(string(c.Source("dense")), "user") {
+		t.Errorf("dense should come from the user file, got %v (%s)", c.Dense, c.Source("dense"))
+	}
+	if len(c.Args) != 1 || c.Args[0] != "10" {
+		t.Errorf("positional args = %v", c.Args)
+	}
+}
+
+func TestLoadRejectsUnknownValues(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, ProjectFile), "thme = \"mono-chill\"\n")
+
+	if _, err := Load(project, nil); err == nil || !strings.Contains(err.Error(), `did you mean "theme"`) {
+		t.Fatalf("expected unknown-key suggestion, got %v", err)
+	}
+
+	os.Remove(filepath.Join(project, ProjectFile))
+	_, err := Load(project, []string{"--theme", "dracula-vibes"})
+	if err == nil || !strings.Contains(err.Error(), `did you mean "dracula-vibe"`) || !strings.Contains(err.Error(), "flag --theme") {
+		t.Fatalf("expected theme suggestion naming the flag, got %v", err)
+	}
+
+	t.Setenv("VIBEPUP_NOTIFY_WATCHDOG", "bell,notify_send")
+	if _, err := Load(project, nil); err == nil || !strings.Contains(err.Error(), `did you mean "notify-send"`) {
+		t.Fatalf("expected channel suggestion, got %v", err)
+	}
+}
+
+func TestLoadKeysTables(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeFile(t, filepath.Join(home, "vibepup", "config.toml"), "[keys]\nquit = [\"ctrl+q\", \"ctrl+c\"]\nlog.wrap = \"W\"\n")
+	writeFile(t, filepath.Join(project, ProjectFile), "[keys.running]\nquit = \"Q\"\n\n[keys]\n\"log.wrap\" = \"alt+w\"\n")
+
+	c, err := Load(project, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := map[string][]string{"quit": {"ctrl+q", "ctrl+c"}, "log.wrap": {"alt+w"}, "running.quit": {"Q"}}
+	if fmt.Sprint(c.Keys) != fmt.Sprint(want) {
+		t.Errorf("Keys = %v, want %v", c.Keys, want)
+	}
+	if src := string(c.Source("keys.log.wrap")); !strings.HasPrefix(src, "project") {
+		t.Errorf("log.wrap should come from the project file, got %q", src)
+	}
+
+	writeFile(t, filepath.Join(project, ProjectFile), "[keys]\nquit = 3\n")
+	if _, err := Load(project, nil); err == nil || !strings.Contains(err.Error(), "keys.quit") {
+		t.Errorf("expected a type error for keys.quit, got %v", err)
+	}
+}
+
+func TestUpdateFileKeepsCommentsAndLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, path, `# my vibes
+theme = "mono-chill"   # easy on the eyes
+quiet = true
+
+[watchdog]
+# be patient
+max_turn_seconds = 900
+`)
+
+	err := UpdateFile(path, map[string]any{
+		"theme":                      "halloween-glitch",
+		"snark":                      "spicy",
+		"watchdog.max_turn_seconds":  1200,
+		"watchdog.no_output_seconds": 240,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := `# my vibes
+theme = "halloween-glitch"   # easy on the eyes
+quiet = true
+snark = "spicy"
+
+[watchdog]
+# be patient
+max_turn_seconds = 1200
+no_output_seconds = 240
+`
+	if string(got) != want {
+		t.Fatalf("unexpected file:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpdateFileFindsDottedAndQuotedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, path, `"theme" = "mono-chill"
+watchdog.max_turn_seconds = 900 # set by hand
+
+[layout]
+"side_width" = 30
+`)
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	err := UpdateFile(path, map[string]any{
+		"theme":                      "halloween-glitch",
+		"watchdog.max_turn_seconds":  1200,
+		"watchdog.no_output_seconds": 240,
+		"layout.side_width":          40,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	want := `"theme" = "halloween-glitch"
+watchdog.max_turn_seconds = 1200 # set by hand
+watchdog.no_output_seconds = 240
+
+[layout]
+"side_width" = 40
+`
+	if string(got) != want {
+		t.Fatalf("unexpected file:\n%s\nwant:\n%s", got, want)
+	}
+	// The file still loads: nothing was defined twice.
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	project := filepath.Dir(path)
+	if err := os.Rename(path, filepath.Join(project, ProjectFile)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(project, nil)
+	if err != nil || c.Watchdog.MaxTurnSeconds != 1200 || c.Watchdog.NoOutputSeconds != 240 || c.Layout.SideWidth != 40 {
+		t.Errorf("Load after the update = %+v, %v", c.Watchdog, err)
+	}
+	if fi, err := os.Stat(filepath.Join(project, ProjectFile)); err != nil || fi.Mode().Perm() != 0o640 {
+		t.Errorf("the file's mode should be kept, got %v, %v", fi.Mode(), err)
+	}
+
+	// A key in an inline table can't be rewritten in place; it isn't
+	// added a second time either.
+	inline := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, inline, "watchdog = { max_turn_seconds = 900 }\n")
+	if err := UpdateFile(inline, map[string]any{"watchdog.max_turn_seconds": 1200}); err == nil {
+		t.Error("updating a key in an inline table should fail")
+	}
+}
+
+func TestUpdateFileRoundTripsStrings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, path, `runner = 'bin/#1' # my fork
+theme = "mono-chill"
+`)
+	secret := "tab\tbell\adel\x7fquote\"back\\é"
+	err := UpdateFile(path, map[string]any{"runner": "bin/#2", "webhook.secret": secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(got), `runner = "bin/#2" # my fork`+"\n") {
+		t.Errorf("a '#' in a literal string was taken for a comment:\n%s", got)
+	}
+	var back struct {
+		Runner  string
+		Webhook struct{ Secret string }
+	}
+	if _, err := toml.Decode(string(got), &back); err != nil {
+		t.Fatalf("the file no longer parses: %v\n%s", err, got)
+	}
+	if back.Runner != "bin/#2" || back.Webhook.Secret != secret {
+		t.Errorf("read back runner %q, secret %q", back.Runner, back.Webhook.Secret)
+	}
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectFile is the per-project config file name, looked up in the project dir.
+const ProjectFile = ".vibepup.toml"
+
+// UserPath returns the per-user config file location.
+func UserPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "vibepup", "config.toml")
+}
+
+// ProjectPath returns the project config file location for dir.
+func ProjectPath(dir string) string {
+	return filepath.Join(dir, ProjectFile)
+}
+
+// Load merges, in increasing order of precedence, the built-in defaults, the
+// user config file, the project config file, VIBEPUP_* environment variables
+// and the command-line flags in args, then validates the result.
+func Load(dir string, args []string) (Config, error) {
+	c := Default()
+
+	for _, layer := range []struct {
+		path  string
+		label string
+	}{
+		{UserPath(), "user"},
+		{ProjectPath(dir), "project"},
+	} {
+		if err := c.loadFile(layer.path, layer.label); err != nil {
+			return c, err
+		}
+	}
+
+	if err := c.loadEnv(os.LookupEnv); err != nil {
+		return c, err
+	}
+
+	if err := c.loadFlags(args); err != nil {
+		return c, err
+	}
+
+	return c, c.Validate()
+}
+
+func (c *Config) loadFile(path, label string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	src := FileSource(label, path)
+
+	raw := map[string]any{}
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return fmt.Errorf("%s: %w", tildify(path), err)
+	}
+	values := map[string]any{}
+	flatten("", raw, values)
+
+	var errs []error
+	for _, key := range sortedKeys(values) {
+		if id, ok := strings.CutPrefix(key, KeysTable+"."); ok {
+			if err := c.setKeys(id, values[key], src); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", tildify(path), err))
+			}
+			continue
+		}
+		f, ok := lookupField(key)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q%s", tildify(path), key, Suggest(key, fieldKeys())))
+			continue
+		}
+		if err := c.setValue(f, values[key], src); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// KeysTable is the config table key bindings are read from.
+const KeysTable = "keys"
+
+// setKeys stores the keys bound to an action: one key name or a list.
+func (c *Config) setKeys(id string, v any, src Source) error {
+	var keys []string
+	switch v := v.(type) {
+	case string:
+		keys = []string{v}
+	case []any:
+		for _, k := range v {
+			s, ok := k.(string)
+			if !ok {
+				return fmt.Errorf("%s.%s: expected key names, got %v", KeysTable, id, k)
+			}
+			keys = append(keys, s)
+		}
+	default:
+		return fmt.Errorf("%s.%s: expected a key name or a list of them, got %v", KeysTable, id, v)
+	}
+	if c.Keys == nil {
+		c.Keys = map[string][]string{}
+	}
+	c.Keys[id] = keys
+	c.setSource(KeysTable+"."+id, src)
+	return nil
+}
+
+// flatten turns nested TOML tables into dotted keys.
+func flatten(prefix string, in map[string]any, out map[string]any) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]any); ok {
+			flatten(key, sub, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	for _, f := range fields {
+		name := envName(f.key)
+		if v, ok := lookup(name); ok {
+			if err := c.setString(f, v, Source("env "+name)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) loadFlags(args []string) error {
+	fs := flag.NewFlagSet("vibepup-tui", flag.ContinueOnError)
+	for _, f := range fields {
+		name := flagName(f.key)
+		src := Source("flag --" + name)
+		usage := fmt.Sprintf("%s (default %s)", f.usage, Default().value(f))
+		set := func(v string) error { return c.setString(f, v, src) }
+		if _, ok := f.ptr(c).(*bool); ok {
+			fs.BoolFunc(name, usage, set)
+		} else {
+			fs.Func(name, usage, set)
+		}
+	}
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "print the effective config with the source of each value, then exit")
+	fs.BoolVar(&c.JSON, "json", false, "run headless, without the TUI, printing one JSON event per line; the exit code gives the outcome")
+	fs.BoolVar(&c.StatusLine, "status-line", false, "print the status of the project (or of the directory given) as one line, e.g. for tmux, then exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c.Args = fs.Args()
+	return nil
+}
+
+// Print writes the effective config, one setting per line, with its source.
+func (c Config) Print(w io.Writer) {
+	width := 0
+	for _, f := range fields {
+		width = max(width, len(f.key))
+	}
+	for _, f := range fields {
+		fmt.Fprintf(w, "%-*s = %-16s # %s\n", width, f.key, c.value(f), c.Source(f.key))
+	}
+	ids := make([]string, 0, len(c.Keys))
+	for id := range c.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		key := KeysTable + "." + id
+		fmt.Fprintf(w, "%-*s = %-16s # %s\n", width, key, fmt.Sprintf("%q", c.Keys[id]), c.Source(key))
+	}
+}
+
+// FileSource is the Source recorded for values read from (or saved to) a
+// config file, e.g. "project .vibepup.toml".
+func FileSource(label, path string) Source {
+	return Source(label + " " + tildify(path))
+}
+
+func tildify(path string) string {
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
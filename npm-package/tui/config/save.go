@@ -0,0 +1,352 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"vibepup-tui/internal/atomicfile"
+)
+
+var (
+	bareKey   = regexp.MustCompile(`^[A-Za-z0-9_\-]+`)
+	tableTail = regexp.MustCompile(`^\s*\]\s*(#.*)?$`)
+)
+
+// parseKey reads a TOML key, bare, quoted or dotted, at the start of s. It
+// returns the key's parts and the length of its text.
+func parseKey(s string) ([]string, int, bool) {
+	var parts []string
+	i := 0
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i == len(s) {
+			return nil, 0, false
+		}
+		switch s[i] {
+		case '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, 0, false
+			}
+			part, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, 0, false
+			}
+			parts, i = append(parts, part), j+1
+		case '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return nil, 0, false
+			}
+			parts, i = append(parts, s[i+1:i+1+j]), i+j+2
+		default:
+			m := bareKey.FindString(s[i:])
+			if m == "" {
+				return nil, 0, false
+			}
+			parts, i = append(parts, m), i+len(m)
+		}
+		end := i
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i == len(s) || s[i] != '.' {
+			return parts, end, true
+		}
+		i++
+	}
+}
+
+// keyLine splits a "key = value" line into its indentation, key text, the
+// " = " between, the value with any comment, and the key's parts.
+func keyLine(line string) (indent, key, eq, rest string, parts []string, ok bool) {
+	body := strings.TrimLeft(line, " \t")
+	indent = line[:len(line)-len(body)]
+	if body == "" || body[0] == '#' || body[0] == '[' {
+		return "", "", "", "", nil, false
+	}
+	parts, n, ok := parseKey(body)
+	if !ok {
+		return "", "", "", "", nil, false
+	}
+	after := strings.TrimLeft(body[n:], " \t")
+	if !strings.HasPrefix(after, "=") {
+		return "", "", "", "", nil, false
+	}
+	after = after[1:]
+	value := strings.TrimLeft(after, " \t")
+	eq = body[n : len(body)-len(value)]
+	return indent, body[:n], eq, value, parts, true
+}
+
+// tableHeader reads a "[table]" line. Array tables ("[[x]]") come back
+// with ok set and a name no setting can have.
+func tableHeader(line string) (string, bool) {
+	body := strings.TrimSpace(line)
+	if strings.HasPrefix(body, "[[") {
+		return "[[array]]", true
+	}
+	if !strings.HasPrefix(body, "[") {
+		return "", false
+	}
+	parts, n, ok := parseKey(body[1:])
+	if !ok || !tableTail.MatchString(body[1+n:]) {
+		return "", false
+	}
+	return strings.Join(parts, "."), true
+}
+
+// Set stores a value for key and records it as coming from src. It is used
+// by the settings screen to apply edits to the running config.
+func (c *Config) Set(key string, v any, src Source) error {
+	f, ok := lookupField(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if n, ok := v.(int); ok {
+		v = int64(n)
+	}
+	return c.setValue(f, v, src)
+}
+
+// Get returns the current value for key as a string, bool or int.
+func (c Config) Get(key string) any {
+	f, ok := lookupField(key)
+	if !ok {
+		return nil
+	}
+	switch p := f.ptr(&c).(type) {
+	case *string:
+		return *p
+	case *bool:
+		return *p
+	case *int:
+		return *p
+	}
+	return nil
+}
+
+// Overridden reports whether key is currently set by a layer that takes
+// precedence over config files (environment or flags), meaning a value
+// saved to a file would not take effect.
+func (c Config) Overridden(key string) bool {
+	s := string(c.Source(key))
+	return strings.HasPrefix(s, "env ") || strings.HasPrefix(s, "flag ")
+}
+
+// UpdateFile rewrites the given keys in a TOML config file in place. Existing
+// lines keep their indentation and trailing comments, untouched lines are
+// preserved verbatim, and missing keys are added to the right table (which
+// is created if needed). Keys are found however they are written: under a
+// table header, dotted ("watchdog.max_turn_seconds = 900") or quoted. The
+// file is replaced atomically.
+func UpdateFile(path string, values map[string]any) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var raw map[string]any
+	md, err := toml.Decode(string(data), &raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	pending := map[string]any{}
+	for k, v := range values {
+		pending[k] = v
+	}
+
+	// Pass 1: replace keys that already exist, remembering where each
+	// table's last key-bearing line is so new keys can be appended there.
+	// A table only made by dotted keys, such as "watchdog" in
+	// "watchdog.max_turn_seconds = 900", is remembered with the header
+	// its keys sit under, as a new key has to be dotted the same way.
+	type group struct {
+		header string
+		end    int
+	}
+	table := ""
+	tableEnd := map[string]int{"": -1}
+	dotted := map[string]group{}
+	firstTable := len(lines)
+	for i, line := range lines {
+		if name, ok := tableHeader(line); ok {
+			table = name
+			tableEnd[table] = i
+			if firstTable == len(lines) {
+				firstTable = i
+			}
+			continue
+		}
+		indent, keyText, eq, rest, parts, ok := keyLine(line)
+		if !ok {
+			continue
+		}
+		tableEnd[table] = i
+		key := strings.Join(parts, ".")
+		if table != "" {
+			key = table + "." + key
+		}
+		for n := 1; n < len(parts); n++ {
+			prefix := strings.Join(parts[:n], ".")
+			if table != "" {
+				prefix = table + "." + prefix
+			}
+			dotted[prefix] = group{header: table, end: i}
+		}
+		v, ok := pending[key]
+		if !ok {
+			continue
+		}
+		lines[i] = indent + keyText + eq + formatValue(v) + trailingComment(rest)
+		delete(pending, key)
+	}
+
+	// Pass 2: insert the rest, top-level keys before the first table.
+	for _, key := range sortedKeys(pending) {
+		if md.IsDefined(strings.Split(key, ".")...) {
+			// Set some way this can't rewrite, e.g. in an inline table;
+			// adding it again would make the file invalid.
+			return fmt.Errorf("%s: can't update %s where it is set; edit the file by hand", path, key)
+		}
+		table, name := "", key
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			table, name = key[:i], key[i+1:]
+		}
+		at, ok := tableEnd[table]
+		g, isDotted := dotted[table]
+		if isDotted && !ok {
+			// Dot the new key the way its siblings are, under their header.
+			at, ok = g.end, true
+			name = strings.TrimPrefix(key, g.header+".")
+			table = g.header
+		}
+		entry := name + " = " + formatValue(pending[key])
+		switch {
+		case table == "" && at < 0:
+			at = firstTable - 1
+		case !ok:
+			if len(lines) > 0 && lines[len(lines)-1] != "" {
+				lines = append(lines, "")
+			}
+			lines = append(lines, "["+table+"]")
+			at = len(lines) - 1
+		}
+		lines = append(lines[:at+1], append([]string{entry}, lines[at+1:]...)...)
+		for t, end := range tableEnd {
+			if end > at || t == table {
+				tableEnd[t] = end + 1
+			}
+		}
+		for t, d := range dotted {
+			if d.end > at {
+				d.end++
+				dotted[t] = d
+			}
+		}
+		if isDotted {
+			g.end = at + 1
+			dotted[key[:strings.LastIndex(key, ".")]] = g
+		}
+		tableEnd[table] = at + 1
+		if at < firstTable {
+			firstTable++
+		}
+	}
+
+	return atomicfile.Write(path, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// trailingComment returns the " # ..." suffix of a value, if any. Quoted
+// strings are skipped so a '#' inside a value isn't mistaken for a comment.
+func trailingComment(rest string) string {
+	var in byte
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; c {
+		case '\\':
+			// Only basic strings have escapes; literal ones are taken as is.
+			if in == '"' {
+				i++
+			}
+		case '"', '\'':
+			if in == 0 {
+				in = c
+			} else if in == c {
+				in = 0
+			}
+		case '#':
+			if in == 0 {
+				j := i
+				for j > 0 && (rest[j-1] == ' ' || rest[j-1] == '\t') {
+					j--
+				}
+				return rest[j:]
+			}
+		}
+	}
+	return ""
+}
+
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return quote(x)
+	case bool:
+		return strconv.FormatBool(x)
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	}
+	return quote(fmt.Sprint(v))
+}
+
+// quote writes s as a TOML basic string. strconv.Quote won't do: TOML has
+// no \x escapes.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
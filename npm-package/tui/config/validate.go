@@ -0,0 +1,195 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"vibepup-tui/animations"
+	"vibepup-tui/motion"
+	"vibepup-tui/notify"
+	"vibepup-tui/persona"
+	"vibepup-tui/theme"
+	"vibepup-tui/ui"
+)
+
+// Check validates a single value for key without applying it.
+func Check(key string, v any) error {
+	f, ok := lookupField(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if f.check == nil {
+		return nil
+	}
+	return f.check(v)
+}
+
+// Validate checks every setting against the theme, snark, animation and
+// effect registries. Errors name the layer the bad value came from.
+func (c Config) Validate() error {
+	var errs []error
+	for _, f := range fields {
+		if f.check == nil {
+			continue
+		}
+		if err := f.check(c.Get(f.key)); err != nil {
+			errs = append(errs, fmt.Errorf("%s = %s (%s): %w", f.key, c.value(f), c.Source(f.key), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkTheme(v any) error {
+	name, _ := v.(string)
+	if _, ok := theme.Lookup(name); ok {
+		return nil
+	}
+	return unknown("theme", name, theme.Names())
+}
+
+func checkSnark(v any) error {
+	var names []string
+	for _, l := range persona.Levels() {
+		if string(l) == v {
+			return nil
+		}
+		names = append(names, string(l))
+	}
+	return unknown("snark level", fmt.Sprint(v), names)
+}
+
+func checkAnim(v any) error {
+	name, _ := v.(string)
+	if _, ok := animations.Lookup(name); ok {
+		return nil
+	}
+	return unknown("animation preset", name, animations.Names())
+}
+
+func checkFX(v any) error {
+	for _, name := range motion.Effects() {
+		if name == v {
+			return nil
+		}
+	}
+	return unknown("effect", fmt.Sprint(v), motion.Effects())
+}
+
+func checkPositive(v any) error {
+	if n, _ := v.(int); n <= 0 {
+		return errors.New("must be greater than zero")
+	}
+	return nil
+}
+
+func checkNonNegative(v any) error {
+	if n, _ := v.(int); n < 0 {
+		return errors.New("must not be negative")
+	}
+	return nil
+}
+
+func checkPreset(v any) error {
+	name, _ := v.(string)
+	if _, ok := ui.Preset(name); ok {
+		return nil
+	}
+	return unknown("layout preset", name, ui.PresetNames())
+}
+
+func checkPanes(v any) error {
+	s, _ := v.(string)
+	_, err := ui.ParsePanes(s)
+	return err
+}
+
+func checkChannels(v any) error {
+	s, _ := v.(string)
+	var names []string
+	for _, c := range notify.Channels() {
+		names = append(names, string(c))
+	}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "none" {
+			continue
+		}
+		if _, err := notify.ParseChannels(name); err != nil {
+			return unknown("notification channel", name, names)
+		}
+	}
+	return nil
+}
+
+func checkURLs(v any) error {
+	s, _ := v.(string)
+	for _, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%q is not an http or https URL", raw)
+		}
+	}
+	return nil
+}
+
+func checkEvents(v any) error {
+	s, _ := v.(string)
+	var names []string
+	for _, k := range notify.Kinds() {
+		names = append(names, string(k))
+	}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(names, name) {
+			return unknown("event type", name, names)
+		}
+	}
+	return nil
+}
+
+func unknown(what, got string, valid []string) error {
+	return fmt.Errorf("unknown %s %q%s; choose one of: %s", what, got, Suggest(got, valid), strings.Join(valid, ", "))
+}
+
+// Suggest returns a "did you mean" hint for the closest candidate, if any is
+// close enough to be a plausible typo.
+func Suggest(got string, candidates []string) string {
+	best, bestDist := "", len(got)/2+2
+	for _, c := range candidates {
+		if d := distance(got, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// distance is the Levenshtein edit distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"vibepup-tui/config"
+	"vibepup-tui/control"
+)
+
+// controlTimeout is how long a control request waits for the TUI.
+const controlTimeout = 5 * time.Second
+
+// controlMsg carries a request from the control socket into Update, which
+// answers on reply.
+type controlMsg struct {
+	req   control.Request
+	reply chan control.Response
+}
+
+// controlCommands are the short names scripts use, and the actions they
+// run. Any other action ID works too.
+var controlCommands = map[string]string{
+	"pause":  "run.pause",
+	"resume": "run.resume",
+	"stop":   "run.stop",
+	"finish": "run.finish",
+	"nudge":  "run.nudge",
+	"detach": "run.detach",
+	"append": "task.add",
+}
+
+// stateNames are the screens as the control API reports them.
+var stateNames = map[viewState]string{
+	stateSplash:   "splash",
+	stateSetup:    "setup",
+	stateRunning:  "running",
+	stateDone:     "done",
+	stateSettings: "settings",
+	stateHistory:  "history",
+	stateQuit:     "quit",
+	stateAddTask:  "add_task",
+}
+
+// serveControl answers requests on ln for p until the returned function
+// is called.
+func serveControl(ln net.Listener, p *tea.Program) func() {
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		control.Serve(ln, func(req control.Request) control.Response {
+			reply := make(chan control.Response, 1)
+			go p.Send(controlMsg{req, reply})
+			select {
+			case resp := <-reply:
+				return resp
+			case <-time.After(controlTimeout):
+				return control.Response{Error: "the TUI didn't answer"}
+			}
+		})
+	}()
+	return func() {
+		ln.Close()
+		<-served
+	}
+}
+
+// control runs a request from the control socket the way the matching
+// key would, and reports the status after it.
+func (m *model) control(req control.Request) (control.Response, tea.Cmd) {
+	cmd, err := m.runControl(req)
+	status := m.status()
+	resp := control.Response{OK: err == nil, Status: &status}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp, cmd
+}
+
+func (m *model) runControl(req control.Request) (tea.Cmd, error) {
+	switch req.Cmd {
+	case "status":
+		return nil, nil
+	case "append":
+		// The text comes with the request, so there's no form to fill in.
+		return nil, m.appendTask(req.Text)
+	}
+	id := req.Cmd
+	if alias, ok := controlCommands[id]; ok {
+		id = alias
+	}
+	for _, a := range actions {
+		if a.ID != id {
+			continue
+		}
+		if m.paletteOpen || !m.available(a) {
+			return nil, fmt.Errorf("%s isn't available on the %s screen right now", req.Cmd, stateNames[m.state])
+		}
+		return a.Run(m), nil
+	}
+	return nil, fmt.Errorf("unknown command %q%s", req.Cmd, config.Suggest(req.Cmd, controlNames()))
+}
+
+// controlNames lists the commands the control API takes.
+func controlNames() []string {
+	names := []string{"status"}
+	for name := range controlCommands {
+		names = append(names, name)
+	}
+	for _, a := range actions {
+		names = append(names, a.ID)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// status describes the session for the control API.
+func (m model) status() control.Status {
+	s := control.Status{
+		Project:     m.projectDir,
+		State:       stateNames[m.state],
+		Run:         control.RunIdle,
+		Running:     m.runner != nil,
+		Stopping:    m.stopping,
+		Waiting:     m.waiting,
+		Phase:       m.phase,
+		Iteration:   m.iteration,
+		Iterations:  m.iterLimit,
+		Model:       m.model,
+		TasksDone:   m.tasks.Done(),
+		TasksTotal:  m.tasks.Total(),
+		LastEvent:   m.lastEvent,
+		LastEventAt: m.lastEventAt,
+		Started:     m.runStarted,
+	}
+	if m.runner != nil {
+		s.Paused = m.runner.Paused()
+		s.PID = m.runner.Pid()
+	}
+	switch {
+	case s.Stopping:
+		s.Run = control.RunStopping
+	case s.Paused:
+		s.Run = control.RunPaused
+	case s.Waiting:
+		s.Run = control.RunWaiting
+	case s.Running:
+		s.Run = control.RunRunning
+	case !m.run.Ended.IsZero():
+		s.Run = control.RunFinished
+	}
+	if t, ok := m.tasks.Next(); ok {
+		s.Task = t.Text
+	}
+	return s
+}
+
+// ctl sends one command to the project's TUI and prints the response:
+// "vibepup-tui ctl <command> [text]".
+func ctl(dir string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: vibepup-tui ctl status|pause|resume|stop|finish|nudge|detach|<action ID> or ctl append <task>")
+	}
+	c, err := control.Dial(dir)
+	if err != nil {
+		return errors.New("no TUI is running in this project")
+	}
+	defer c.Close()
+	resp, err := c.Do(control.Request{Cmd: args[0], Text: strings.Join(args[1:], " ")})
+	if resp.Status != nil {
+		out, _ := json.Marshal(resp)
+		fmt.Fprintln(os.Stdout, string(out))
+	}
+	return err
+}
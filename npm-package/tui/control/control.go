@@ -0,0 +1,200 @@
+// Package control is the local API a running TUI serves so scripts and
+// editor plugins can drive it: one JSON request per line on a Unix socket
+// in the project, each answered by one JSON response.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"vibepup-tui/internal/sockfile"
+)
+
+// SocketPath is where the project's TUI listens.
+func SocketPath(dir string) string {
+	return filepath.Join(dir, ".ralph", "control.sock")
+}
+
+// Request is a command for the TUI. Cmd is "status", one of the short
+// names such as "pause", "stop", "nudge" and "append", or any action ID
+// from the command palette. Text is the task for "append".
+type Request struct {
+	Cmd  string `json:"cmd"`
+	Text string `json:"text,omitempty"`
+}
+
+// Response answers a request. Every response carries the status as of
+// after the command ran; OK is false and Error says why when it couldn't.
+type Response struct {
+	OK     bool    `json:"ok"`
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Status describes the session. It is also kept in .ralph/status.json
+// for tools that only want to look.
+type Status struct {
+	Project string `json:"project"` // the project directory
+	// State is the screen the TUI is on: "splash", "setup", "running" and
+	// so on.
+	State string `json:"state"`
+	// Run is the runner's state: one of the Run* constants.
+	Run      string `json:"run"`
+	Running  bool   `json:"running"`
+	Paused   bool   `json:"paused,omitempty"`
+	Stopping bool   `json:"stopping,omitempty"`
+	// Waiting is a watch-mode runner idling until the PRD changes, which
+	// a nudge ends.
+	Waiting bool `json:"waiting,omitempty"`
+	PID     int  `json:"pid,omitempty"`
+
+	Phase      string `json:"phase,omitempty"`
+	Iteration  int    `json:"iteration,omitempty"`
+	Iterations int    `json:"iterations,omitempty"` // 0 in watch mode
+	Model      string `json:"model,omitempty"`
+
+	Task       string `json:"task,omitempty"` // the first unchecked task
+	TasksDone  int    `json:"tasks_done"`
+	TasksTotal int    `json:"tasks_total"`
+
+	// LastEvent describes the last runner event, e.g. "loop 3 (BUILD)".
+	LastEvent   string    `json:"last_event,omitempty"`
+	LastEventAt time.Time `json:"last_event_at,omitzero"`
+	Started     time.Time `json:"started,omitzero"`
+	// Updated is when the status file was written.
+	Updated time.Time `json:"updated,omitzero"`
+}
+
+// Runner states.
+const (
+	RunIdle     = "idle" // no runner started yet
+	RunRunning  = "running"
+	RunPaused   = "paused"
+	RunWaiting  = "waiting"
+	RunStopping = "stopping"
+	RunFinished = "finished"
+	// RunDetached is a runner left to a supervisor by a TUI that quit.
+	RunDetached = "detached"
+	// RunExited is the TUI gone without leaving a runner behind.
+	RunExited = "exited"
+)
+
+// Handler answers one request.
+type Handler func(Request) Response
+
+// Listen opens the project's control socket, clearing one left behind by
+// a TUI that's gone.
+func Listen(dir string) (net.Listener, error) {
+	path := SocketPath(dir)
+	ln, err := sockfile.Listen(path)
+	if errors.Is(err, sockfile.ErrInUse) {
+		return nil, fmt.Errorf("another TUI is already listening on %s", path)
+	}
+	return ln, err
+}
+
+// Serve answers requests with h until ln is closed, then closes the
+// connections still open. Requests on one connection are answered in
+// order.
+func Serve(ln net.Listener, h Handler) error {
+	var (
+		mu    sync.Mutex
+		open  = map[net.Conn]bool{}
+		conns sync.WaitGroup
+	)
+	defer func() {
+		mu.Lock()
+		for conn := range open {
+			conn.Close()
+		}
+		mu.Unlock()
+		conns.Wait()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		open[conn] = true
+		mu.Unlock()
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			serveConn(conn, h)
+			mu.Lock()
+			delete(open, conn)
+			mu.Unlock()
+		}()
+	}
+}
+
+func serveConn(conn net.Conn, h Handler) {
+	defer conn.Close()
+	sc := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var req Request
+		resp := Response{Error: "bad request: want a JSON object such as {\"cmd\": \"status\"}"}
+		if err := json.Unmarshal(sc.Bytes(), &req); err == nil {
+			resp = h(req)
+		}
+		if enc.Encode(resp) != nil {
+			return
+		}
+	}
+}
+
+// Client is a connection to the project's TUI.
+type Client struct {
+	conn net.Conn
+	dec  *json.Decoder
+	enc  *json.Encoder
+}
+
+// Dial connects to the project's TUI.
+func Dial(dir string) (*Client, error) {
+	conn, err := net.Dial("unix", SocketPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient speaks the protocol over conn.
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, dec: json.NewDecoder(conn), enc: json.NewEncoder(conn)}
+}
+
+// Do sends req and waits for the answer. A command the TUI refused is
+// reported as an error along with its response.
+func (c *Client) Do(req Request) (Response, error) {
+	if err := c.enc.Encode(req); err != nil {
+		return Response{}, err
+	}
+	var resp Response
+	if err := c.dec.Decode(&resp); err != nil {
+		return Response{}, err
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,92 @@
+package control
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestServeAnswersInOrder(t *testing.T) {
+	dir := t.TempDir()
+	ln, err := Listen(dir)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	var got []Request
+	paused := false
+	served := make(chan error)
+	go func() {
+		served <- Serve(ln, func(req Request) Response {
+			got = append(got, req)
+			switch req.Cmd {
+			case "pause":
+				paused = true
+			case "status", "append":
+			default:
+				return Response{Error: fmt.Sprintf("unknown command %q", req.Cmd)}
+			}
+			return Response{OK: true, Status: &Status{State: "running", Running: true, Paused: paused}}
+		})
+	}()
+
+	if _, err := Listen(dir); err == nil {
+		t.Error("a second Listen should see the socket is taken")
+	}
+	c, err := Dial(dir)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	resp, err := c.Do(Request{Cmd: "status"})
+	if err != nil || resp.Status == nil || resp.Status.Paused {
+		t.Fatalf("status = %+v, %v", resp, err)
+	}
+	if resp, err = c.Do(Request{Cmd: "pause"}); err != nil || !resp.Status.Paused {
+		t.Errorf("pause = %+v, %v", resp, err)
+	}
+	if _, err = c.Do(Request{Cmd: "append", Text: "Write the docs"}); err != nil {
+		t.Errorf("append: %v", err)
+	}
+	if _, err = c.Do(Request{Cmd: "explode"}); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("an unknown command should come back as an error, got %v", err)
+	}
+	// A client still connected doesn't hold up shutting down.
+	ln.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+	if len(got) != 4 || got[2].Text != "Write the docs" {
+		t.Errorf("handler saw %+v", got)
+	}
+	if _, err := c.Do(Request{Cmd: "status"}); err == nil {
+		t.Error("the connection should be closed with the listener")
+	}
+
+	// A socket left behind by a TUI that's gone is cleared.
+	ln, err = Listen(dir)
+	if err != nil {
+		t.Fatalf("Listen over a stale socket: %v", err)
+	}
+	ln.Close()
+}
+
+func TestServeRejectsBadJSON(t *testing.T) {
+	server, conn := net.Pipe()
+	go serveConn(server, func(Request) Response { return Response{OK: true} })
+	defer conn.Close()
+
+	if _, err := fmt.Fprintln(conn, "pause please"); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(conn)
+	var resp Response
+	if err := c.dec.Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.OK || !strings.Contains(resp.Error, "bad request") {
+		t.Errorf("got %+v, want a bad request error", resp)
+	}
+	if _, err := c.Do(Request{Cmd: "status"}); err != nil {
+		t.Errorf("the connection should carry on after a bad line: %v", err)
+	}
+}
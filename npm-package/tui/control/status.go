@@ -0,0 +1,73 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vibepup-tui/internal/atomicfile"
+)
+
+// StatusPath is where the TUI keeps the project's status.
+func StatusPath(dir string) string {
+	return filepath.Join(dir, ".ralph", "status.json")
+}
+
+// WriteStatus replaces the project's status file in one step, so readers
+// never see half of it.
+func WriteStatus(dir string, s Status) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(StatusPath(dir), append(data, '\n'))
+}
+
+// ReadStatus reads the project's status file.
+func ReadStatus(dir string) (Status, error) {
+	var s Status
+	data, err := os.ReadFile(StatusPath(dir))
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", StatusPath(dir), err)
+	}
+	return s, nil
+}
+
+// Line is the status as a compact one-liner for a tmux status bar or a
+// shell prompt, e.g. "🐾 BUILD 3/5 · gpt-5.2-codex · 4/7 tasks". ascii
+// leaves out the emoji.
+func (s Status) Line(ascii bool) string {
+	prefix, sep := "🐾", " · "
+	if ascii {
+		prefix, sep = "vibepup", " | "
+	}
+	var parts []string
+	if s.Run != RunRunning {
+		parts = append(parts, s.Run)
+	}
+	if s.Running && s.Iteration > 0 {
+		loop := fmt.Sprint(s.Iteration)
+		if s.Iterations > 0 {
+			loop += fmt.Sprintf("/%d", s.Iterations)
+		}
+		if s.Phase != "" {
+			loop = s.Phase + " " + loop
+		}
+		parts = append(parts, loop)
+	}
+	if s.Running && s.Model != "" {
+		parts = append(parts, s.Model[strings.LastIndex(s.Model, "/")+1:])
+	}
+	if s.TasksTotal > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d tasks", s.TasksDone, s.TasksTotal))
+	}
+	if len(parts) == 0 {
+		return prefix
+	}
+	return prefix + " " + strings.Join(parts, sep)
+}
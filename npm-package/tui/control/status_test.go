@@ -0,0 +1,50 @@
+package control
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStatusFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadStatus(dir); !os.IsNotExist(err) {
+		t.Errorf("no status file should read as not existing, got %v", err)
+	}
+	want := Status{Project: dir, State: "running", Run: RunRunning, Running: true, Phase: "BUILD", Iteration: 3,
+		TasksDone: 4, TasksTotal: 7, LastEvent: "tool Bash", LastEventAt: time.Now().UTC().Round(time.Second)}
+	if err := WriteStatus(dir, want); err != nil {
+		t.Fatalf("WriteStatus: %v", err)
+	}
+	got, err := ReadStatus(dir)
+	if err != nil {
+		t.Fatalf("ReadStatus: %v", err)
+	}
+	if got != want {
+		t.Errorf("ReadStatus = %+v, want %+v", got, want)
+	}
+	if entries, _ := os.ReadDir(dir + "/.ralph"); len(entries) != 1 {
+		t.Errorf("the temp file should be gone, .ralph has %d entries", len(entries))
+	}
+}
+
+func TestStatusLine(t *testing.T) {
+	cases := []struct {
+		s     Status
+		ascii bool
+		want  string
+	}{
+		{Status{Run: RunRunning, Running: true, Phase: "BUILD", Iteration: 3, Iterations: 5, Model: "openai/gpt-5.2-codex", TasksDone: 4, TasksTotal: 7}, false,
+			"🐾 BUILD 3/5 · gpt-5.2-codex · 4/7 tasks"},
+		{Status{Run: RunPaused, Running: true, Paused: true, Phase: "PLAN", Iteration: 1}, true,
+			"vibepup paused | PLAN 1"},
+		{Status{Run: RunRunning, Running: true}, true, "vibepup"},
+		{Status{Run: RunFinished, Iteration: 5, Model: "openai/gpt-5.2", TasksDone: 7, TasksTotal: 7}, false,
+			"🐾 finished · 7/7 tasks"},
+	}
+	for _, tc := range cases {
+		if got := tc.s.Line(tc.ascii); got != tc.want {
+			t.Errorf("Line(%+v) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"vibepup-tui/config"
+	"vibepup-tui/process"
+	"vibepup-tui/session"
+	"vibepup-tui/summary"
+	"vibepup-tui/tasks"
+	"vibepup-tui/ui"
+)
+
+// runnerCommand is the command the runner is started with.
+func runnerCommand(cfg config.Config) string {
+	if cfg.Runner != "" {
+		return cfg.Runner
+	}
+	return "vibepup"
+}
+
+// runnerEnv is what the runner's environment gets on top of the TUI's.
+func runnerEnv(cfg config.Config) []string {
+	var env []string
+	if w := cfg.Watchdog; w.MaxTurnSeconds > 0 && w.NoOutputSeconds > 0 {
+		env = append(env,
+			fmt.Sprintf("RALPH_MAX_TURN_SECONDS=%d", w.MaxTurnSeconds),
+			fmt.Sprintf("RALPH_NO_OUTPUT_SECONDS=%d", w.NoOutputSeconds),
+		)
+	}
+	return env
+}
+
+// startDaemon starts a supervisor in the background, running the runner
+// with the arguments after "daemon", and returns once it's listening.
+func startDaemon(dir string, cfg config.Config) error {
+	if c, err := session.Dial(dir); err == nil {
+		c.Close()
+		return fmt.Errorf("a runner is already going here (PID %d); use vibepup-tui attach", c.Meta.PID)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// The same flags, with the subcommand swapped for the hidden one.
+	args := slices.Clone(os.Args[1:])
+	args[len(args)-len(cfg.Args)] = session.SuperviseCommand
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if c, err := session.Dial(dir); err == nil {
+			c.Close()
+			fmt.Printf("Runner started in the background (PID %d), logging to %s\n", c.Meta.PID, c.Meta.Log)
+			fmt.Println("Attach with: vibepup-tui attach")
+			return nil
+		}
+		select {
+		case err := <-exited:
+			return fmt.Errorf("the supervisor exited early: %v", err)
+		case <-deadline:
+			return errors.New("the supervisor didn't start listening")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// supervise runs the runner under a supervisor until it exits.
+func supervise(dir string, cfg config.Config) error {
+	ln, err := session.Listen(dir)
+	if err != nil {
+		return err
+	}
+	args := cfg.Args[1:]
+	if cfg.Design {
+		args = append(args, "--design")
+	}
+	started := time.Now()
+	runner, done := process.Start(context.Background(), runnerCommand(cfg), args, runnerEnv(cfg))
+	if runner == nil {
+		ln.Close()
+		msg, _ := done().(process.DoneMsg)
+		return msg.Err
+	}
+	store := ui.NewLineStore(cfg.Log.Scrollback, sessionLogPath(dir, started))
+	defer store.Close()
+	meta := session.Meta{
+		PID:      runner.Pid(),
+		Runner:   runnerCommand(cfg),
+		Args:     args,
+		Started:  started,
+		Detached: started,
+		Log:      store.Path(),
+	}
+	if err := session.Save(dir, meta); err != nil {
+		runner.Kill()
+		ln.Close()
+		return err
+	}
+	feed := superviseAlerts(dir, cfg, args, started)
+	defer feed.close()
+	feed.started(started)
+	sup := &session.Supervisor{Dir: dir, Meta: meta, Runner: runner, Store: store, Replay: cfg.Log.Scrollback,
+		Watch: feed.line, Exit: feed.exit}
+	return sup.Serve(ln, done)
+}
+
+// superviseAlerts notifies and posts webhooks for a supervised run, so a
+// background run reports itself with no TUI attached. Attached TUIs leave
+// it to the supervisor, bar their own terminal's bell and OSC sequences.
+func superviseAlerts(dir string, cfg config.Config, args []string, started time.Time) *alertFeed {
+	list, _ := tasks.Load(dir)
+	feed := &alertFeed{alerts: newAlerts(dir, list, cfg), dir: dir, list: list, run: summary.New(args, started)}
+	// The supervisor's stderr is the terminal it was started from, if any,
+	// which may have moved on to other things.
+	feed.notifier.Term = nil
+	return feed
+}
+
+// adopt supervises the runner a detaching TUI handed over, carrying on
+// its session log.
+func adopt(dir string, cfg config.Config) error {
+	meta, err := session.Load(dir)
+	if err != nil {
+		return err
+	}
+	runner, done := process.Adopt(meta.PID, os.NewFile(3, "stdout"), os.NewFile(4, "stderr"))
+	store := ui.ResumeLineStore(cfg.Log.Scrollback, meta.Log)
+	defer store.Close()
+	// Without the socket the output is still logged, just not attachable.
+	ln, err := session.Listen(dir)
+	if err != nil {
+		ln = nil
+	}
+	// The TUI that detached reported the start of the run.
+	feed := superviseAlerts(dir, cfg, meta.Args, meta.Started)
+	defer feed.close()
+	feed.watcher.Start(meta.Started)
+	sup := &session.Supervisor{Dir: dir, Meta: meta, Runner: runner, Store: store, Replay: cfg.Log.Scrollback,
+		Watch: feed.line, Exit: feed.exit}
+	return sup.Serve(ln, done)
+}
+
+// attachTUI opens the TUI on the project's supervised runner.
+func attachTUI(dir string, cfg config.Config) error {
+	c, err := session.Dial(dir)
+	if err != nil {
+		meta, lerr := session.Load(dir)
+		switch {
+		case lerr != nil:
+			return fmt.Errorf("nothing to attach to: no supervisor on %s", session.SocketPath(dir))
+		case meta.Running():
+			return fmt.Errorf("runner PID %d is going, but its supervisor isn't answering on %s", meta.PID, session.SocketPath(dir))
+		default:
+			return fmt.Errorf("nothing to attach to: the last background run ended %s; its log is %s", meta.Ended.Format(time.DateTime), meta.Log)
+		}
+	}
+	cfg.Args = nil
+	// The supervisor posts the webhooks and desktop notifications.
+	cfg.Webhook.URLs = ""
+	m := initialModel(cfg)
+	m.alerts.notifier.Desktop = nil
+	// The supervisor keeps the session log; this TUI only needs the window.
+	m.viewport = m.newViewport(ui.NewLineStore(cfg.Log.Scrollback, ""))
+	m.startup = m.attach(c)
+	runTUI(m, cfg)
+	return nil
+}
+
+// attach shows the supervisor's runner as if this TUI had started it: the
+// replayed scrollback is parsed like live output, so the panes catch up.
+func (m *model) attach(c *session.Client) tea.Cmd {
+	meta := c.Meta
+	m.attached = &meta
+	m.state = stateRunning
+	m.selected, m.args = "", meta.Args
+	m.runStarted = meta.Started
+	m.iterLimit = iterationLimit(meta.Args)
+	m.run = summary.New(meta.Args, meta.Started)
+	m.tasksAtStart = m.tasks
+	m.replaying = c.Replay
+	m.dogState = "running"
+	m.viewport.WriteNote(fmt.Sprintf("--- Attached to runner PID %d; the full log is %s ---", meta.PID, meta.Log))
+	var done tea.Cmd
+	m.runner, done = c.Runner()
+	// The run started before this TUI did, and the supervisor reported it.
+	m.alerts.watcher.Start(meta.Started)
+	return tea.Batch(done, m.runner.WaitForOutput())
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"vibepup-tui/summary"
+	"vibepup-tui/ui"
+)
+
+// maxDoneTasks limits how many newly completed tasks are listed by name.
+const maxDoneTasks = 5
+
+// rerun starts the runner again from the summary screen.
+func (m *model) rerun() tea.Cmd {
+	m.state = stateRunning
+	m.iteration, m.phase, m.model = 0, "", ""
+	m.notice = ""
+	m.resize()
+	return m.startProcess()
+}
+
+// doneView is the summary of the finished run.
+func (m model) doneView() string {
+	r := m.run
+	title := lipgloss.NewStyle().Foreground(m.theme.Accent).Bold(true)
+	label := lipgloss.NewStyle().Foreground(m.theme.Muted)
+	value := lipgloss.NewStyle().Foreground(m.theme.Foreground)
+	good := lipgloss.NewStyle().Foreground(m.theme.Highlight)
+	bad := lipgloss.NewStyle().Foreground(m.theme.AccentAlt)
+
+	var rows []string
+	row := func(k, v string) {
+		rows = append(rows, label.Render(fmt.Sprintf("%-11s", k))+v)
+	}
+	more := func(v string) {
+		rows = append(rows, strings.Repeat(" ", 11)+v)
+	}
+
+	heading := "RUN FINISHED"
+	if r.ExitCode != 0 {
+		heading = "RUN STOPPED"
+	}
+	rows = append(rows, title.Render(heading), "")
+
+	row("duration", value.Render(ui.FormatDuration(r.Duration().Round(time.Second))))
+	row("iterations", value.Render(fmt.Sprint(r.Iterations)))
+	exit := good
+	if r.ExitCode != 0 {
+		exit = bad
+	}
+	row("exit", exit.Render(fmt.Sprintf("%d, %s", r.ExitCode, summary.ExitMeaning(r.ExitCode))))
+	if r.Completed {
+		row("agent", good.Render("reported the PRD complete"))
+	}
+
+	check := "✓ "
+	if m.asciiOnly() {
+		check = "[x] "
+	}
+	done := m.tasks.CompletedSince(m.tasksAtStart)
+	row("tasks", value.Render(fmt.Sprintf("%d completed this run, %d/%d done", len(done), m.tasks.Done(), m.tasks.Total())))
+	for i, t := range done {
+		if i == maxDoneTasks {
+			more(label.Render(fmt.Sprintf("… and %d more", len(done)-i)))
+			break
+		}
+		more(good.Render(check) + value.Render(t.Text))
+	}
+
+	kills := value
+	if r.WatchdogKills > 0 {
+		kills = bad
+	}
+	row("watchdog", kills.Render(fmt.Sprintf("%d turn(s) killed", r.WatchdogKills)))
+
+	models := r.Models()
+	if len(models) == 0 {
+		row("models", label.Render("none started"))
+	}
+	for i, s := range models {
+		line := value.Render(fmt.Sprintf("%-36s ", s.Model)) + outcomes(s.Outcomes, good, bad)
+		if i == 0 {
+			row("models", line)
+		} else {
+			more(line)
+		}
+	}
+
+	if path := m.viewport.Store().Path(); path != "" {
+		if rel, err := filepath.Rel(m.projectDir, path); err == nil {
+			path = rel
+		}
+		row("log", value.Render(path))
+	}
+	if m.notice != "" {
+		rows = append(rows, "", bad.Render(m.notice))
+	}
+	rows = append(rows, "", m.help.View(m.helpKeys()))
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+// outcomes lists a model's turn outcomes, successes first.
+func outcomes(counts map[string]int, good, bad lipgloss.Style) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == summary.OK) != (names[j] == summary.OK) {
+			return names[i] == summary.OK
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		style := bad
+		if name == summary.OK {
+			style = good
+		}
+		parts[i] = style.Render(fmt.Sprintf("%d %s", counts[name], name))
+	}
+	return strings.Join(parts, ", ")
+}
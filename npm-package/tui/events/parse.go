@@ -0,0 +1,131 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Kind identifies a runner event recognised in the output stream.
+type Kind string
+
+const (
+	Loop Kind = "loop"
+	// Tool is an opencode tool-call header; the tool's output follows it.
+	Tool Kind = "tool"
+	// Model is the runner starting a turn with a model.
+	Model Kind = "model"
+	// Result is the runner giving up on a model's turn; Result says why.
+	Result Kind = "result"
+	// Complete is the agent reporting the PRD done.
+	Complete Kind = "complete"
+	// Logs names the directory the iteration's agent output is kept in.
+	Logs Kind = "logs"
+	// Waiting is the runner in watch mode idling until prd.md changes.
+	Waiting Kind = "waiting"
+	// Exhausted is every model in the chain failing an iteration.
+	Exhausted Kind = "exhausted"
+	// Watchdog is the runner's watchdog killing a turn that ran too long
+	// or went silent; Result says which.
+	Watchdog Kind = "watchdog"
+)
+
+// Results of a model's turn, as reported by the runner.
+const (
+	Failed      = "failed"
+	Unsupported = "unsupported"
+	// Watchdog results.
+	Timeout  = "timeout"
+	NoOutput = "no_output"
+)
+
+// Event is a structured view of a runner output line.
+type Event struct {
+	Kind      Kind
+	Iteration int
+	Phase     string
+	Tool      string // tool name, e.g. Bash
+	Title     string // what the call does, or its raw arguments
+	Model     string // e.g. openai/gpt-5.2
+	Result    string // Failed or Unsupported, or Timeout or NoOutput
+	ExitCode  int
+	Path      string
+}
+
+// Describe puts the event in a few words, e.g. "loop 3 (BUILD)".
+func (e Event) Describe() string {
+	switch e.Kind {
+	case Loop:
+		return fmt.Sprintf("loop %d (%s)", e.Iteration, e.Phase)
+	case Tool:
+		return "tool " + e.Tool
+	case Model:
+		return "model " + e.Model
+	case Result:
+		if e.Result == Failed {
+			return fmt.Sprintf("model %s failed (exit %d)", e.Model, e.ExitCode)
+		}
+		return fmt.Sprintf("model %s %s", e.Model, e.Result)
+	case Logs:
+		return "logs " + e.Path
+	case Watchdog:
+		return "watchdog " + strings.ReplaceAll(e.Result, "_", " ")
+	}
+	return string(e.Kind)
+}
+
+var (
+	loopLine  = regexp.MustCompile(`Loop (\d+) \((\w+) Phase\)`)
+	modelLine = regexp.MustCompile(`^Using: (\S+)$`)
+	failLine  = regexp.MustCompile(`Model (\S+) failed \(Exit: (-?\d+)\)`)
+	unsupLine = regexp.MustCompile(`Model (\S+) not supported`)
+	logsLine  = regexp.MustCompile(`^Logs: (\S.*)$`)
+	// opencode pads the tool name into a column, so it is followed by at
+	// least two spaces; that keeps markdown table rows from matching.
+	toolLine = regexp.MustCompile(`^\|\s+([A-Za-z][\w.-]*)\s{2,}(\S.*)$`)
+)
+
+// Parse recognises the status lines printed by the vibepup runner and the
+// tool-call headers printed by opencode.
+func Parse(line string) (Event, bool) {
+	line = strings.TrimSpace(ansi.Strip(line))
+	if m := loopLine.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return Event{Kind: Loop, Iteration: n, Phase: m[2]}, true
+	}
+	if m := modelLine.FindStringSubmatch(line); m != nil {
+		return Event{Kind: Model, Model: m[1]}, true
+	}
+	if m := failLine.FindStringSubmatch(line); m != nil {
+		code, _ := strconv.Atoi(m[2])
+		return Event{Kind: Result, Model: m[1], Result: Failed, ExitCode: code}, true
+	}
+	if m := unsupLine.FindStringSubmatch(line); m != nil {
+		return Event{Kind: Result, Model: m[1], Result: Unsupported}, true
+	}
+	if strings.Contains(line, "Agent signaled completion") {
+		return Event{Kind: Complete}, true
+	}
+	if strings.Contains(line, "All models failed this iteration") {
+		return Event{Kind: Exhausted}, true
+	}
+	if strings.Contains(line, "Waiting for changes in prd.md") {
+		return Event{Kind: Waiting}, true
+	}
+	if strings.HasPrefix(line, "[RALPH] TIMEOUT") {
+		return Event{Kind: Watchdog, Result: Timeout}, true
+	}
+	if strings.HasPrefix(line, "[RALPH] NO OUTPUT") {
+		return Event{Kind: Watchdog, Result: NoOutput}, true
+	}
+	if m := logsLine.FindStringSubmatch(line); m != nil {
+		return Event{Kind: Logs, Path: m[1]}, true
+	}
+	if m := toolLine.FindStringSubmatch(line); m != nil && !strings.HasSuffix(m[2], "|") {
+		return Event{Kind: Tool, Tool: m[1], Title: m[2]}, true
+	}
+	return Event{}, false
+}
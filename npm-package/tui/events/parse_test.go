@@ -0,0 +1,51 @@
+package events
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		line string
+		want Event
+		ok   bool
+	}{
+		{"\x1b[36mLoop 3 (Build Phase)\x1b[0m", Event{Kind: Loop, Iteration: 3, Phase: "Build"}, true},
+		{"\x1b[91m\x1b[1m| \x1b[0m\x1b[90m Bash     \x1b[0mList top-level directory contents", Event{Kind: Tool, Tool: "Bash", Title: "List top-level directory contents"}, true},
+		{"| ast_grep_search  {\"pattern\":\"export $$$\"}", Event{Kind: Tool, Tool: "ast_grep_search", Title: "{\"pattern\":\"export $$$\"}"}, true},
+		{"   Using: google/gemini-3-pro-preview", Event{Kind: Model, Model: "google/gemini-3-pro-preview"}, true},
+		{"   ⚠️  Model openai/gpt-5.2 failed (Exit: 1). Falling back...", Event{Kind: Result, Model: "openai/gpt-5.2", Result: Failed, ExitCode: 1}, true},
+		{"   ⚠️  Model opencode/glm-4.7-free not supported. Falling back...", Event{Kind: Result, Model: "opencode/glm-4.7-free", Result: Unsupported}, true},
+		{"✅ Agent signaled completion.", Event{Kind: Complete}, true},
+		{"❌ All models failed this iteration.", Event{Kind: Exhausted}, true},
+		{"⏸️  Project Complete. Waiting for changes in prd.md...", Event{Kind: Waiting}, true},
+		{"   Logs: /work/.ralph/runs/iter-0003", Event{Kind: Logs, Path: "/work/.ralph/runs/iter-0003"}, true},
+		{"[RALPH] TIMEOUT: killing opencode turn", Event{Kind: Watchdog, Result: Timeout}, true},
+		{"[RALPH] NO OUTPUT: likely waiting for input / hung tool", Event{Kind: Watchdog, Result: NoOutput}, true},
+		{"| name | value |", Event{}, false},
+		{"| Bash    |", Event{}, false},
+		{"1076 |     const info = provider.models[modelID]", Event{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := Parse(tc.line)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	cases := []struct {
+		ev   Event
+		want string
+	}{
+		{Event{Kind: Loop, Iteration: 3, Phase: "BUILD"}, "loop 3 (BUILD)"},
+		{Event{Kind: Result, Model: "openai/gpt-5.2", Result: Failed, ExitCode: 1}, "model openai/gpt-5.2 failed (exit 1)"},
+		{Event{Kind: Result, Model: "opencode/glm-4.7-free", Result: Unsupported}, "model opencode/glm-4.7-free unsupported"},
+		{Event{Kind: Complete}, "complete"},
+		{Event{Kind: Watchdog, Result: NoOutput}, "watchdog no output"},
+	}
+	for _, tc := range cases {
+		if got := tc.ev.Describe(); got != tc.want {
+			t.Errorf("Describe(%+v) = %q, want %q", tc.ev, got, tc.want)
+		}
+	}
+}
@@ -2,16 +2,17 @@ module vibepup-tui
 
 go 1.24.2
 
-	require (
-		github.com/Nomadcxx/sysc-Go v1.0.2
-		github.com/charmbracelet/bubbles v0.21.0
-		github.com/charmbracelet/bubbletea v1.3.10
-		github.com/charmbracelet/huh v0.6.0
-		github.com/charmbracelet/lipgloss v1.1.0
-		github.com/charmbracelet/x/exp/teatest v0.0.0-20260126174759-33beb0ebb156
-		github.com/mattn/go-isatty v0.0.20
-	)
-
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/Nomadcxx/sysc-Go v1.0.2
+	github.com/charmbracelet/bubbles v0.21.0
+	github.com/charmbracelet/bubbletea v1.3.10
+	github.com/charmbracelet/huh v0.6.0
+	github.com/charmbracelet/lipgloss v1.1.0
+	github.com/charmbracelet/x/ansi v0.10.1
+	github.com/charmbracelet/x/exp/teatest v0.0.0-20260126174759-33beb0ebb156
+	github.com/mattn/go-isatty v0.0.20
+)
 
 require (
 	github.com/atotto/clipboard v0.1.4 // indirect
@@ -21,11 +22,9 @@ require (
 	github.com/charmbracelet/colorprofile v0.3.2 // indirect
 	github.com/charmbracelet/lipgloss/v2 v2.0.0-beta.3.0.20250917201909-41ff0bf215ea // indirect
 	github.com/charmbracelet/ultraviolet v0.0.0-20250915111650-81d4262876ef // indirect
-	github.com/charmbracelet/x/ansi v0.10.1 // indirect
 	github.com/charmbracelet/x/cellbuf v0.0.13 // indirect
 	github.com/charmbracelet/x/exp/golden v0.0.0-20241011142426-46044092ad91 // indirect
 	github.com/charmbracelet/x/exp/strings v0.0.0-20240722160745-212f7b056ed0 // indirect
-	github.com/charmbracelet/x/exp/teatest v0.0.0-20260126174759-33beb0ebb156 // indirect
 	github.com/charmbracelet/x/term v0.2.1 // indirect
 	github.com/charmbracelet/x/termios v0.1.1 // indirect
 	github.com/charmbracelet/x/windows v0.2.2 // indirect
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"vibepup-tui/config"
+	"vibepup-tui/events"
+	"vibepup-tui/problems"
+	"vibepup-tui/process"
+	"vibepup-tui/summary"
+	"vibepup-tui/tasks"
+)
+
+// outcomeExitCodes are the exit codes of a run without the TUI. A runner
+// that fails or is stopped passes its own code through instead.
+var outcomeExitCodes = map[string]int{
+	summary.OutcomeComplete:      0,
+	summary.OutcomeMaxIterations: 3,
+	summary.OutcomeModelsFailed:  4,
+	summary.OutcomeWatchdog:      5,
+}
+
+// jsonEvent is one line of --json output. Type is "start", "output" for
+// each line the runner prints, a runner event kind such as "loop" or
+// "model", "problem" for a compiler or test failure in the output, "task"
+// for a task checked off in the PRD, and finally "exit".
+type jsonEvent struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+
+	Text      string   `json:"text,omitempty"`
+	Project   string   `json:"project,omitempty"`
+	Args      []string `json:"args,omitempty"`
+	PID       int      `json:"pid,omitempty"`
+	Iteration int      `json:"iteration,omitempty"`
+	Phase     string   `json:"phase,omitempty"`
+	Model     string   `json:"model,omitempty"`
+	Tool      string   `json:"tool,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	Result    string   `json:"result,omitempty"`
+	Path      string   `json:"path,omitempty"`
+	Task      string   `json:"task,omitempty"`
+
+	Problem *jsonProblem `json:"problem,omitempty"`
+
+	// The exit event sums the run up.
+	Outcome         string  `json:"outcome,omitempty"`
+	ExitCode        *int    `json:"exit_code,omitempty"`
+	RunnerExitCode  *int    `json:"runner_exit_code,omitempty"`
+	Iterations      int     `json:"iterations,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	TasksDone       *int    `json:"tasks_done,omitempty"`
+	TasksTotal      *int    `json:"tasks_total,omitempty"`
+	WatchdogKills   int     `json:"watchdog_kills,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+type jsonProblem struct {
+	Source   string `json:"source"`
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+// runSink receives what a run without the TUI produces, to show it one
+// way or another.
+type runSink interface {
+	start(dir string, args []string, pid int)
+	// line is an output line and the event parsed from it, if any.
+	line(text string, ev events.Event, ok bool)
+	problem(iteration int, p problems.Problem)
+	taskDone(t tasks.Task)
+	// exit sums the run up; code is what the process exits with.
+	exit(run summary.Run, list tasks.List, code int)
+}
+
+// runPlain runs the runner without the TUI: the same event parsing,
+// problem extraction and run summary, reported to sink. It returns the
+// exit code for the outcome.
+func runPlain(dir string, cfg config.Config, sink runSink) int {
+	args := cfg.Args
+	if cfg.Design {
+		args = append(args, "--design")
+	}
+	list, _ := tasks.Load(dir)
+	notes := newAlerts(dir, list, cfg)
+	defer notes.close()
+	run := summary.New(args, time.Now())
+	runner, done := process.Start(context.Background(), runnerCommand(cfg), args, runnerEnv(cfg))
+	if runner == nil {
+		msg, _ := done().(process.DoneMsg)
+		run.Finish(msg.Err, time.Now())
+		return finish(sink, run, list)
+	}
+	sink.start(dir, args, runner.Pid())
+	notes.started(run.Started)
+
+	// ctrl+c or a CI cancel stops the runner gracefully; a second one
+	// kills it.
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		<-sig
+		runner.Stop(process.StopStep)
+		<-sig
+		runner.Kill()
+	}()
+
+	var extractor problems.Extractor
+	for line := range runner.OutputChan {
+		ev, ok := events.Parse(line)
+		if ok {
+			run.Feed(ev)
+		}
+		sink.line(line, ev, ok)
+		notes.line(line, ev, ok)
+		if ok && ev.Kind == events.Loop {
+			extractor = problems.Extractor{}
+		}
+		for _, p := range extractor.Feed(line) {
+			sink.problem(run.Iterations, p)
+		}
+		if ok && touchesTasks(ev) {
+			list = tasksDone(sink, dir, list)
+			notes.tasks(list)
+		}
+	}
+	msg, _ := done().(process.DoneMsg)
+	run.Finish(msg.Err, time.Now())
+	list = tasksDone(sink, dir, list)
+	notes.tasks(list)
+	notes.finished(run.Outcome())
+	return finish(sink, run, list)
+}
+
+// tasksDone reports the tasks checked off since before, and returns the
+// PRD as it is now.
+func tasksDone(sink runSink, dir string, before tasks.List) tasks.List {
+	now, err := tasks.Load(dir)
+	if err != nil {
+		return before
+	}
+	for _, t := range now.CompletedSince(before) {
+		sink.taskDone(t)
+	}
+	return now
+}
+
+// finish reports the end of the run and returns the exit code.
+func finish(sink runSink, run summary.Run, list tasks.List) int {
+	code, ok := outcomeExitCodes[run.Outcome()]
+	if !ok {
+		code = run.ExitCode
+	}
+	sink.exit(run, list, code)
+	return code
+}
+
+// runHeadless is --json: the run written to w as one JSON event per line.
+func runHeadless(w io.Writer, dir string, cfg config.Config) int {
+	return runPlain(dir, cfg, &jsonSink{enc: json.NewEncoder(w)})
+}
+
+// jsonSink writes jsonEvents.
+type jsonSink struct {
+	enc *json.Encoder
+}
+
+func (s *jsonSink) emit(ev jsonEvent) {
+	ev.Time = time.Now()
+	_ = s.enc.Encode(ev)
+}
+
+func (s *jsonSink) start(dir string, args []string, pid int) {
+	s.emit(jsonEvent{Type: "start", Project: dir, Args: args, PID: pid})
+}
+
+func (s *jsonSink) line(text string, ev events.Event, ok bool) {
+	s.emit(jsonEvent{Type: "output", Text: ansi.Strip(text)})
+	if ok {
+		s.emit(eventJSON(ev))
+	}
+}
+
+func (s *jsonSink) problem(iteration int, p problems.Problem) {
+	s.emit(jsonEvent{Type: "problem", Iteration: iteration, Problem: &jsonProblem{Source: p.Source, Location: p.Location(), Message: p.Message}})
+}
+
+func (s *jsonSink) taskDone(t tasks.Task) {
+	s.emit(jsonEvent{Type: "task", Task: t.Text})
+}
+
+func (s *jsonSink) exit(run summary.Run, list tasks.List, code int) {
+	ev := jsonEvent{
+		Type:            "exit",
+		Outcome:         run.Outcome(),
+		ExitCode:        &code,
+		RunnerExitCode:  &run.ExitCode,
+		Iterations:      run.Iterations,
+		DurationSeconds: run.Duration().Round(time.Millisecond).Seconds(),
+		WatchdogKills:   run.WatchdogKills,
+	}
+	done, total := list.Done(), list.Total()
+	ev.TasksDone, ev.TasksTotal = &done, &total
+	if run.Err != nil {
+		ev.Error = run.Err.Error()
+	}
+	s.emit(ev)
+}
+
+// eventJSON converts a runner event.
+func eventJSON(ev events.Event) jsonEvent {
+	out := jsonEvent{Type: string(ev.Kind), Iteration: ev.Iteration, Phase: ev.Phase, Model: ev.Model,
+		Tool: ev.Tool, Title: ev.Title, Result: ev.Result, Path: ev.Path}
+	if ev.Kind == events.Result && ev.Result == events.Failed {
+		out.ExitCode = &ev.ExitCode
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"vibepup-tui/process"
+)
+
+// sessionLogs lists the session logs kept in the project, newest first.
+func sessionLogs(dir string) []string {
+	paths, _ := filepath.Glob(filepath.Join(filepath.Dir(sessionLogPath(dir, time.Time{})), "session-*.log"))
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths
+}
+
+// historyLabel describes a session log by when it started and its size.
+func historyLabel(path string) string {
+	label := filepath.Base(path)
+	if t, err := time.ParseInLocation("session-20060102-150405.log", label, time.Local); err == nil {
+		label = t.Format("Mon 2 Jan 15:04:05")
+	}
+	if info, err := os.Stat(path); err == nil {
+		label += fmt.Sprintf("  (%d KB)", (info.Size()+1023)/1024)
+	}
+	return label
+}
+
+// openHistory lists earlier sessions; the picked one opens in $EDITOR.
+func (m *model) openHistory() tea.Cmd {
+	logs := sessionLogs(m.projectDir)
+	if len(logs) == 0 {
+		m.notice = "No session logs in " + filepath.Dir(sessionLogPath(m.projectDir, time.Time{})) + " yet."
+		if m.state == stateRunning {
+			m.viewport.WriteNote(m.notice)
+		}
+		return nil
+	}
+	options := make([]huh.Option[string], len(logs))
+	for i, path := range logs {
+		options[i] = huh.NewOption(historyLabel(path), path)
+	}
+
+	keymap := huh.NewDefaultKeyMap()
+	keymap.Quit = key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "back"))
+	m.historyPick = new(string)
+	m.history = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Session history").
+				Description("Opens the full log in $EDITOR").
+				Options(options...).
+				Value(m.historyPick),
+		),
+	).WithTheme(huh.ThemeDracula()).WithKeyMap(keymap)
+	m.prevState = m.state
+	m.state = stateHistory
+	return m.history.Init()
+}
+
+func (m model) updateHistory(msg tea.Msg) (model, tea.Cmd) {
+	form, cmd := m.history.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.history = f
+	}
+	switch m.history.State {
+	case huh.StateCompleted:
+		m.state = m.prevState
+		path := *m.historyPick
+		return m, tea.ExecProcess(process.EditorCmd(path, 1, 1), func(err error) tea.Msg {
+			return process.EditorDoneMsg{Path: path, Err: err}
+		})
+	case huh.StateAborted:
+		m.state = m.prevState
+	}
+	return m, cmd
+}
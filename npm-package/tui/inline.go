@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"vibepup-tui/ui"
+)
+
+// inlineLines is how many of the latest log lines the inline view shows.
+const inlineLines = 5
+
+// printFinished prints the log lines not printed yet above the inline
+// view, where they stay in the terminal's scrollback. It's called as each
+// iteration finishes, so the view only ever holds the current one.
+func (m *model) printFinished() tea.Cmd {
+	if !m.inline {
+		return nil
+	}
+	lines := m.unprinted()
+	if len(lines) == 0 {
+		return nil
+	}
+	return tea.Println(strings.Join(lines, "\n"))
+}
+
+// unprinted returns the log lines not yet printed above the inline view
+// and marks them printed.
+func (m *model) unprinted() []string {
+	store := m.viewport.Store()
+	from, to := max(m.printed, store.First()), store.Total()
+	var lines []string
+	for i := from; i < to; i++ {
+		lines = append(lines, store.Line(i))
+	}
+	m.printed = max(m.printed, to)
+	return lines
+}
+
+// inlineView is the running screen in inline mode: a few rows at the
+// bottom of the terminal with the dog, the latest output, the status bar
+// and the short help.
+func (m model) inlineView() string {
+	dog := m.dogView()
+	if m.cfg.Quiet {
+		dog = ""
+	}
+	logWidth := m.width - lipgloss.Width(dog) - 2
+	if dog == "" {
+		logWidth = m.width
+	}
+
+	store := m.viewport.Store()
+	rows := make([]string, inlineLines)
+	for i := range rows {
+		n := store.Total() - inlineLines + i
+		if n >= max(m.printed, store.First()) {
+			rows[i] = ui.ClampWidth(store.Line(n), logWidth)
+		}
+	}
+	body := strings.Join(rows, "\n")
+	if dog != "" {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.NewStyle().PaddingRight(2).Render(dog), body)
+	}
+	help := ui.ClampWidth(m.help.ShortHelpView(m.helpKeys().ShortHelp()), m.width)
+	return body + "\n" + m.statusBar() + "\n" + help
+}
@@ -0,0 +1,27 @@
+// Package sockfile listens on Unix sockets kept at fixed paths, where the
+// last process to listen may have died without removing its socket.
+package sockfile
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ErrInUse is a socket another process still answers on.
+var ErrInUse = errors.New("socket in use")
+
+// Listen listens on path, clearing a socket left behind by a process
+// that's gone and creating its directory if need be.
+func Listen(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, ErrInUse
+	}
+	os.Remove(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,27 @@
+package sockfile
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenClearsAStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "x.sock")
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(path); !errors.Is(err, ErrInUse) {
+		t.Errorf("a live socket: err = %v, want ErrInUse", err)
+	}
+	// Leave the file behind, as a process that died would.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	ln, err = Listen(path)
+	if err != nil {
+		t.Fatalf("a stale socket should be cleared: %v", err)
+	}
+	ln.Close()
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"vibepup-tui/config"
+)
+
+// screens are the screens with keymaps of their own, under the names used
+// for [keys.<screen>] tables.
+var screens = []struct {
+	name  string
+	state viewState
+}{
+	{"splash", stateSplash},
+	{"setup", stateSetup},
+	{"running", stateRunning},
+	{"done", stateDone},
+}
+
+// linkBindings are the keys of the file-reference picker. They aren't
+// actions: they only mean something while a reference is being picked.
+var linkBindings = []struct {
+	ID  string
+	Key func(*KeyMap) *key.Binding
+}{
+	{"link.next", func(k *KeyMap) *key.Binding { return &k.LinkNext }},
+	{"link.prev", func(k *KeyMap) *key.Binding { return &k.LinkPrev }},
+	{"link.open", func(k *KeyMap) *key.Binding { return &k.LinkOpen }},
+	{"link.exit", func(k *KeyMap) *key.Binding { return &k.LinkExit }},
+}
+
+// Keymaps holds the effective key bindings: the defaults with the [keys]
+// overrides applied, then each screen's own.
+type Keymaps struct {
+	base    *KeyMap
+	screens map[viewState]*KeyMap
+}
+
+// For returns the keymap of state s. Screens without one of their own,
+// such as the settings form, use the bindings common to all screens.
+func (k Keymaps) For(s viewState) *KeyMap {
+	if km, ok := k.screens[s]; ok {
+		return km
+	}
+	return k.base
+}
+
+// LoadKeymaps applies the key bindings from the config to the defaults.
+// IDs are action IDs such as "quit", or "running.quit" to rebind for one
+// screen only; an empty list of keys unbinds the action. Unknown actions,
+// unknown key names and keys bound twice on one screen are all errors.
+func LoadKeymaps(overrides map[string][]string) (Keymaps, error) {
+	var errs []error
+	base := DefaultKeyMap()
+	type override struct {
+		id   string
+		keys []string
+	}
+	perScreen := map[viewState][]override{}
+	ids := make([]string, 0, len(overrides))
+	for id := range overrides {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if name, rest, ok := strings.Cut(id, "."); ok {
+			if s, ok := screenNamed(name); ok {
+				perScreen[s] = append(perScreen[s], override{rest, overrides[id]})
+				continue
+			}
+		}
+		if err := rebind(&base, id, overrides[id]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	km := Keymaps{base: &base, screens: map[viewState]*KeyMap{}}
+	for _, sc := range screens {
+		k := base
+		for _, o := range perScreen[sc.state] {
+			if !usedOn(o.id, sc.state) {
+				errs = append(errs, fmt.Errorf("%s.%s: not used on the %s screen", sc.name, o.id, sc.name))
+				continue
+			}
+			if err := rebind(&k, o.id, o.keys); err != nil {
+				errs = append(errs, fmt.Errorf("%s.%w", sc.name, err))
+			}
+		}
+		km.screens[sc.state] = &k
+		errs = append(errs, conflicts(&k, sc.state, sc.name)...)
+	}
+	return km, errors.Join(errs...)
+}
+
+func screenNamed(name string) (viewState, bool) {
+	for _, sc := range screens {
+		if sc.name == name {
+			return sc.state, true
+		}
+	}
+	return 0, false
+}
+
+// bindingFor returns the binding the action id uses in k.
+func bindingFor(k *KeyMap, id string) *key.Binding {
+	for _, a := range actions {
+		if a.ID == id && a.Key != nil {
+			return a.Key(k)
+		}
+	}
+	for _, l := range linkBindings {
+		if l.ID == id {
+			return l.Key(k)
+		}
+	}
+	return nil
+}
+
+// bindableIDs lists the IDs that can be given keys.
+func bindableIDs() []string {
+	var ids []string
+	for _, a := range actions {
+		if a.Key != nil {
+			ids = append(ids, a.ID)
+		}
+	}
+	for _, l := range linkBindings {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+// usedOn reports whether the action id has a key on screen s.
+func usedOn(id string, s viewState) bool {
+	for _, a := range actions {
+		if a.ID == id && slices.Contains(a.States, s) {
+			return true
+		}
+	}
+	return s == stateRunning && strings.HasPrefix(id, "link.")
+}
+
+// rebind replaces the keys of the action id in k, keeping its description.
+func rebind(k *KeyMap, id string, keys []string) error {
+	b := bindingFor(k, id)
+	if b == nil {
+		return fmt.Errorf("%s: unknown action%s", id, config.Suggest(id, bindableIDs()))
+	}
+	if len(keys) == 0 {
+		b.SetEnabled(false)
+		return nil
+	}
+	names := make([]string, len(keys))
+	for i, name := range keys {
+		n, err := keyName(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		names[i] = n
+	}
+	b.SetKeys(names...)
+	b.SetHelp(keyLabel(names), b.Help().Desc)
+	b.SetEnabled(true)
+	return nil
+}
+
+// keyNames are the names Bubble Tea gives keys that don't type a
+// character, such as "ctrl+p", "pgdown" and "alt+up".
+var keyNames = func() map[string]bool {
+	names := map[string]bool{}
+	for t := tea.KeyF20; t <= tea.KeyCtrlQuestionMark; t++ {
+		if t == tea.KeyRunes {
+			continue
+		}
+		for _, alt := range []bool{false, true} {
+			if s := (tea.Key{Type: t, Alt: alt}).String(); s != "" {
+				names[s] = true
+			}
+		}
+	}
+	return names
+}()
+
+// keyName checks a key name as written in the config, in the syntax
+// bubbles/key matches against: a named key or a single character, either
+// optionally prefixed with "alt+". "space" is accepted for " ".
+func keyName(name string) (string, error) {
+	n := name
+	if r, ok := strings.CutSuffix(name, "space"); ok && (r == "" || r == "alt+") {
+		n = r + " "
+	}
+	if keyNames[n] {
+		return n, nil
+	}
+	r := strings.TrimPrefix(n, "alt+")
+	if utf8.RuneCountInString(r) == 1 {
+		if c, _ := utf8.DecodeRuneInString(r); unicode.IsPrint(c) {
+			return n, nil
+		}
+	}
+	known := make([]string, 0, len(keyNames))
+	for k := range keyNames {
+		known = append(known, k)
+	}
+	sort.Strings(known)
+	return "", fmt.Errorf("unknown key %q%s", name, config.Suggest(name, known))
+}
+
+// keyLabel is how keys are shown in the help view.
+func keyLabel(keys []string) string {
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		labels[i] = strings.Replace(k, " ", "space", 1)
+	}
+	return strings.Join(labels, "/")
+}
+
+// conflicts reports keys that are bound to two actions on one screen. An
+// action counts even when it's only available sometimes: a key that
+// changes meaning when, say, a search starts would be a trap.
+func conflicts(k *KeyMap, s viewState, screen string) []error {
+	var errs []error
+	check := func(owners map[string]string, id string, b *key.Binding) {
+		if !b.Enabled() {
+			return
+		}
+		for _, name := range b.Keys() {
+			if other, ok := owners[name]; ok && other != id {
+				errs = append(errs, fmt.Errorf("%s: %q is bound to both %s and %s", screen, keyLabel([]string{name}), other, id))
+				continue
+			}
+			owners[name] = id
+		}
+	}
+
+	owners := map[string]string{}
+	seen := map[*key.Binding]bool{}
+	for _, a := range actions {
+		if a.Key == nil || !slices.Contains(a.States, s) {
+			continue
+		}
+		b := a.Key(k)
+		if seen[b] {
+			continue
+		}
+		seen[b] = true
+		check(owners, a.ID, b)
+	}
+	// The reference picker takes over the keyboard, so its keys only have
+	// to be distinct from each other.
+	if s == stateRunning {
+		owners = map[string]string{}
+		for _, l := range linkBindings {
+			check(owners, l.ID, l.Key(k))
+		}
+	}
+	return errs
+}
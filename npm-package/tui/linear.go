@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"vibepup-tui/config"
+	"vibepup-tui/events"
+	"vibepup-tui/problems"
+	"vibepup-tui/summary"
+	"vibepup-tui/tasks"
+	"vibepup-tui/ui"
+)
+
+// runLinear runs the runner without the TUI when stdout isn't a terminal,
+// e.g. piped to a file or a CI log: plain timestamped lines with a banner
+// per loop, and no cursor movement or animation.
+func runLinear(w io.Writer, dir string, cfg config.Config) int {
+	return runPlain(dir, cfg, &linearSink{w: w, color: linearColor(os.Getenv), ascii: cfg.NoEmoji, clock: time.Now})
+}
+
+// linearColor reports whether to print colour without a terminal: only
+// when FORCE_COLOR asks for it, as a log file or CI page may not render
+// it, and never with NO_COLOR set.
+func linearColor(getenv func(string) string) bool {
+	if getenv("NO_COLOR") != "" {
+		return false
+	}
+	force := getenv("FORCE_COLOR")
+	return force != "" && force != "0" && force != "false"
+}
+
+// linearSink prints the run line by line.
+type linearSink struct {
+	w     io.Writer
+	color bool // otherwise the runner's colours are stripped too
+	ascii bool // no emoji
+	clock func() time.Time
+}
+
+// SGR styles for the sink's own text.
+const (
+	sgrBanner = "1;36"
+	sgrMuted  = "2"
+	sgrGood   = "32"
+	sgrBad    = "31"
+)
+
+func (s *linearSink) paint(sgr, text string) string {
+	if !s.color {
+		return text
+	}
+	return "\x1b[" + sgr + "m" + text + "\x1b[0m"
+}
+
+// clean strips colour, unless it was asked for, and emoji with --no-emoji.
+func (s *linearSink) clean(text string) string {
+	if !s.color {
+		text = ansi.Strip(text)
+	}
+	if s.ascii {
+		stripped := ui.StripEmoji(text)
+		// Don't leave the space that followed a leading emoji.
+		if !strings.HasPrefix(text, " ") {
+			stripped = strings.TrimLeft(stripped, " ")
+		}
+		text = stripped
+	}
+	return text
+}
+
+// print writes one line under a timestamp prefix.
+func (s *linearSink) print(text string) {
+	bar := "│"
+	if s.ascii {
+		bar = "|"
+	}
+	fmt.Fprintf(s.w, "%s %s\n", s.paint(sgrMuted, s.clock().Format(time.TimeOnly)+" "+bar), text)
+}
+
+// banner prints a heading line, e.g. for a new loop.
+func (s *linearSink) banner(sgr, text string) {
+	rule := "━━━"
+	if s.ascii {
+		rule = "==="
+	}
+	s.print(s.paint(sgr, rule+" "+text+" "+rule))
+}
+
+func (s *linearSink) start(dir string, args []string, pid int) {
+	what := "vibepup"
+	if len(args) > 0 {
+		what += " " + strings.Join(args, " ")
+	}
+	s.banner(sgrBanner, fmt.Sprintf("Starting %s (PID %d) in %s", what, pid, dir))
+}
+
+func (s *linearSink) line(text string, ev events.Event, ok bool) {
+	if ok && ev.Kind == events.Loop {
+		s.banner(sgrBanner, fmt.Sprintf("Loop %d · %s phase", ev.Iteration, ev.Phase))
+		return
+	}
+	s.print(s.clean(text))
+}
+
+// problem is already in the output it was found in.
+func (s *linearSink) problem(int, problems.Problem) {}
+
+func (s *linearSink) taskDone(t tasks.Task) {
+	check := "✓"
+	if s.ascii {
+		check = "[x]"
+	}
+	s.print(s.paint(sgrGood, check+" Task done: "+s.clean(t.Text)))
+}
+
+func (s *linearSink) exit(run summary.Run, list tasks.List, code int) {
+	parts := []string{
+		strings.ReplaceAll(run.Outcome(), "_", " "),
+		fmt.Sprintf("%d iteration(s)", run.Iterations),
+		ui.FormatDuration(run.Duration().Round(time.Second)),
+	}
+	if list.Total() > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d tasks", list.Done(), list.Total()))
+	}
+	if run.WatchdogKills > 0 {
+		parts = append(parts, fmt.Sprintf("%d watchdog kill(s)", run.WatchdogKills))
+	}
+	sgr := sgrGood
+	if code != 0 {
+		sgr = sgrBad
+		parts = append(parts, fmt.Sprintf("exit %d", code))
+	}
+	if run.Err != nil && run.ExitCode != 0 {
+		parts = append(parts, summary.ExitMeaning(run.ExitCode))
+	}
+	s.banner(sgr, "Run finished: "+strings.Join(parts, " · "))
+}
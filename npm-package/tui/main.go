@@ -2,22 +2,35 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-isatty"
 
+	"vibepup-tui/activity"
+	"vibepup-tui/animations"
 	"vibepup-tui/config"
+	"vibepup-tui/control"
+	"vibepup-tui/events"
 	"vibepup-tui/motion"
 	"vibepup-tui/persona"
+	"vibepup-tui/problems"
 	"vibepup-tui/process"
+	"vibepup-tui/session"
+	"vibepup-tui/summary"
+	"vibepup-tui/tasks"
 	"vibepup-tui/theme"
 	"vibepup-tui/ui"
 )
@@ -29,6 +42,36 @@ type KeyMap struct {
 	Help      key.Binding
 	NextTheme key.Binding
 	Pet       key.Binding
+	Problems  key.Binding
+	Activity  key.Binding
+	Shell     key.Binding
+	Settings  key.Binding
+	Search    key.Binding
+	SearchNext key.Binding
+	SearchPrev key.Binding
+	Links     key.Binding
+	LinkNext  key.Binding
+	LinkPrev  key.Binding
+	LinkOpen  key.Binding
+	LinkExit  key.Binding
+	Live      key.Binding
+	Wrap      key.Binding
+	Timestamps key.Binding
+	Fold      key.Binding
+	FoldAll   key.Binding
+	FocusNext key.Binding
+	FocusPrev key.Binding
+	Preset    key.Binding
+	Grow      key.Binding
+	Shrink    key.Binding
+	Palette   key.Binding
+	SearchClear key.Binding
+	History   key.Binding
+	Pause     key.Binding
+	Nudge     key.Binding
+	AddTask   key.Binding
+	Rerun     key.Binding
+	Watch     key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
@@ -37,15 +80,42 @@ func DefaultKeyMap() KeyMap {
 		Help: key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
 		NextTheme: key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "theme")),
 		Pet: key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pet dog")),
+		Problems: key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "problems")),
+		Activity: key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "activity")),
+		Shell: key.NewBinding(key.WithKeys("!"), key.WithHelp("!", "shell")),
+		Settings: key.NewBinding(key.WithKeys(","), key.WithHelp(",", "settings")),
+		Search: key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+		SearchNext: key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "next match")),
+		SearchPrev: key.NewBinding(key.WithKeys("N"), key.WithHelp("N", "prev match")),
+		Links: key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open file")),
+		LinkNext: key.NewBinding(key.WithKeys("down", "j", "tab"), key.WithHelp("↓/j", "next ref")),
+		LinkPrev: key.NewBinding(key.WithKeys("up", "k", "shift+tab"), key.WithHelp("↑/k", "prev ref")),
+		LinkOpen: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open in $EDITOR")),
+		LinkExit: key.NewBinding(key.WithKeys("esc", "o"), key.WithHelp("esc", "done")),
+		Live: key.NewBinding(key.WithKeys("G"), key.WithHelp("G", "jump to live")),
+		Wrap: key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "wrap")),
+		Timestamps: key.NewBinding(key.WithKeys("T"), key.WithHelp("T", "timestamps")),
+		Fold: key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "fold tool output")),
+		FoldAll: key.NewBinding(key.WithKeys("Z"), key.WithHelp("Z", "fold/unfold all")),
+		FocusNext: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next pane")),
+		FocusPrev: key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "prev pane")),
+		Preset: key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "layout")),
+		Grow: key.NewBinding(key.WithKeys(">"), key.WithHelp(">", "grow pane")),
+		Shrink: key.NewBinding(key.WithKeys("<"), key.WithHelp("<", "shrink pane")),
+		Palette: key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "commands")),
+		SearchClear: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear search")),
+		History: key.NewBinding(key.WithKeys("h"), key.WithHelp("h", "history")),
+		Pause: key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "pause/resume")),
+		Nudge: key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("ctrl+n", "nudge")),
+		AddTask: key.NewBinding(key.WithKeys("+"), key.WithHelp("+", "add task")),
+		Rerun: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rerun")),
+		Watch: key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "watch mode")),
 	}
 }
 
-func (k KeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Help, k.Quit, k.NextTheme, k.Pet}
-}
-
-func (k KeyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{{k.Help, k.Quit, k.NextTheme, k.Pet}}
+// LinkHelp lists the keys used while picking a file reference.
+func (k KeyMap) LinkHelp() []key.Binding {
+	return []key.Binding{k.LinkNext, k.LinkPrev, k.LinkOpen, k.LinkExit}
 }
 
 // --- Model ---
@@ -57,30 +127,69 @@ const (
 	stateSetup
 	stateRunning
 	stateDone
+	stateSettings
+	stateHistory
+	stateQuit
+	stateAddTask
 )
 
 type model struct {
 	state      viewState
+	prevState  viewState
 	width      int
 	height     int
 	ready      bool
 	
 	// Components
-	keys       KeyMap
+	keymaps    Keymaps
 	help       help.Model
 	form       *huh.Form
 	newForm    *huh.Form
+	settings   *huh.Form
+	settingsDraft *settingsDraft
+	history    *huh.Form
+	historyPick *string
+	quitForm   *huh.Form
+	quitPick   *string
+	taskForm   *huh.Form
+	taskText   *string
+	palette    ui.CommandPalette
+	paletteOpen bool
+	paletteActions []Action
 	viewport   ui.LogViewport
 	spinner    spinner.Model
+	searchInput  textinput.Model
+	searchTyping bool
+	searchRegex  bool
+	searchErr    error
+	searchSeq    int // bumped by each keystroke, to debounce the search
+	problems   *problems.Set
+	showProblems bool
+	activity   *activity.Feed
+	showActivity bool
+	arrangement ui.Arrangement
+	focus      ui.Pane
+	paneScroll map[ui.Pane]int
+	layoutDirty bool // the arrangement changed and isn't saved yet
+	layoutSeq  int  // bumped by each change, to save once it settles
+	tasks      tasks.List
 	
 	// Config & State
-	flags      config.Flags
+	cfg        config.Config
 	theme      theme.Theme
 	styles     lipgloss.Style // Simplified, use theme package directly where possible
 	snark      persona.SnarkLevel
 	
 	// Process
 	runner     *process.Runner
+	stopping   bool // the stop ladder is under way
+	stopAtLoop bool // stop when the next loop starts
+	waiting    bool // a watch-mode runner is idle until the PRD changes
+	quitOnExit bool
+	attached   *session.Meta // the supervisor's session, when attached
+	startup    tea.Cmd       // run by Init, e.g. to start streaming on attach
+	detached   *session.Meta
+	projectDir string
 	selected   string
 	newIdea    string
 	args       []string
@@ -88,30 +197,75 @@ type model struct {
 	// Animation
 	motion     motion.Engine
 	frame      int
+	started    time.Time
 	dogState   string // "sleeping", "running", "barking", "happy"
+
+	// Run progress, from the runner's loop banners
+	iteration  int
+	iterLimit  int // 0 in watch mode
+	phase      string
+	model      string
+	runStarted time.Time
+	turnStarted time.Time
+	lastOutput time.Time
+	lastEvent  string // the last runner event, described
+	lastEventAt time.Time
+	statusFile *statusFile // nil when not kept, as in tests
+	inline     bool // no alt screen: a compact view, with history printed above
+	printed    int  // log lines printed above the inline view so far
+	alerts     alerts
+	replaying  int // replayed lines still to come on attaching, which don't notify
+
+	// The finished run, for the summary screen
+	run        summary.Run
+	tasksAtStart tasks.List
+	notice     string
 }
 
-func initialModel(flags config.Flags) model {
-	th := theme.Get(flags.Theme)
-	snark := persona.ParseSnark(flags.Snark)
+func initialModel(cfg config.Config) model {
+	th := theme.Get(cfg.Theme)
+	snark := persona.ParseSnark(cfg.Snark)
 	
 	s := spinner.New()
 	s.Spinner = spinner.Points // More modern spinner
 	s.Style = lipgloss.NewStyle().Foreground(th.Highlight)
 
+	dir, _ := os.Getwd()
+
+	// main reports bad bindings before we get here; tests get the defaults.
+	keymaps, err := LoadKeymaps(cfg.Keys)
+	if err != nil {
+		keymaps, _ = LoadKeymaps(nil)
+	}
+
 	m := model{
 		state:    stateSplash,
-		keys:     DefaultKeyMap(),
+		projectDir: dir,
+		keymaps:  keymaps,
 		help:     help.New(),
-		flags:    flags,
+		cfg:      cfg,
 		theme:    th,
 		snark:    snark,
-		motion:   motion.New(flags.PerfLow, flags.Quiet),
+		motion:   motion.New(cfg.PerfLow, cfg.Quiet),
+		started:  time.Now(),
 		spinner:  s,
+		searchInput: newSearchInput(),
+		problems: problems.NewSet(),
+		showProblems: true,
+		activity: activity.NewFeed(),
+		showActivity: true,
+		arrangement: arrangementFor(cfg),
+		inline:   cfg.NoAlt,
+		focus:    ui.PaneLog,
+		paneScroll: map[ui.Pane]int{},
 		dogState: "sleeping",
 		selected: "watch", // Default
-		args:     os.Args[1:],
+		args:     cfg.Args,
 	}
+	m.viewport = m.newViewport(ui.NewLineStore(cfg.Log.Scrollback, ""))
+	list, _ := tasks.Load(dir)
+	m.alerts = newAlerts(dir, list, cfg)
+	m.reloadTasks()
 
 	// Setup Form
 	m.form = huh.NewForm(
@@ -145,48 +299,53 @@ func (m model) Init() tea.Cmd {
 	return tea.Batch(
 		m.motion.Next(),
 		m.spinner.Tick,
+		m.startup,
 	)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
+	handled := false // a key ran an action and isn't passed on
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		m.help.Width = msg.Width
-		
-		// Dynamic Layout Calculation
-		headerHeight := 10 // Approximation, should be measured
-		footerHeight := 3
-		vpHeight := msg.Height - headerHeight - footerHeight - 2 // Borders
-		
-		if !m.ready {
-			m.viewport = ui.NewLogViewport(msg.Width-4, vpHeight)
-			m.ready = true
-		} else {
-			m.viewport.SetSize(msg.Width-4, vpHeight)
-		}
+		m.resize()
 
 	case tea.KeyMsg:
-		if m.state == stateRunning && m.runner != nil {
-			// Pass interactions to viewport if needed
+		if m.state == stateSettings {
+			return m.updateSettings(msg)
 		}
-		
-		switch {
-		case key.Matches(msg, m.keys.Quit):
-			if m.runner != nil {
-				m.runner.Kill() // ZOMBIE KILLER
-			}
-			return m, tea.Quit
-		case key.Matches(msg, m.keys.Pet):
-			m.dogState = "happy"
-			cmds = append(cmds, tea.Tick(time.Second, func(t time.Time) tea.Msg {
-				return "dog_reset"
-			}))
+		if m.state == stateHistory {
+			return m.updateHistory(msg)
 		}
+		if m.state == stateQuit {
+			return m.updateQuit(msg)
+		}
+		if m.state == stateAddTask {
+			return m.updateAddTask(msg)
+		}
+		if m.paletteOpen {
+			return m.updatePalette(msg)
+		}
+		if m.searchTyping {
+			return m.updateSearchInput(msg)
+		}
+		if m.viewport.LinkMode() {
+			return m.updateLinkMode(msg)
+		}
+		if a, ok := m.actionFor(msg); ok {
+			cmds = append(cmds, a.Run(&m))
+			handled = true
+		}
+
+	case controlMsg:
+		resp, cmd := m.control(msg.req)
+		msg.reply <- resp
+		cmds = append(cmds, cmd)
 
 	case string:
 		if msg == "dog_reset" {
@@ -199,6 +358,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case motion.TickMsg:
 		m.frame++
+		m.motion.Step()
 		cmds = append(cmds, m.motion.Next())
 
 	case spinner.TickMsg:
@@ -206,69 +366,357 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 
 	case process.OutputMsg:
-		m.viewport.WriteLine(string(msg))
-		cmds = append(cmds, m.runner.WaitForOutput())
+		line := string(msg)
+		if m.asciiOnly() {
+			line = ui.StripEmoji(line)
+		}
+		ev, ok := events.Parse(line)
+		m.lastOutput = time.Now()
+		if ok && ev.Kind != events.Logs {
+			m.lastEvent, m.lastEventAt = ev.Describe(), m.lastOutput
+		}
+		m.run.Feed(ev)
+		if m.replaying > 0 {
+			m.replaying--
+			m.alerts.catchUp(line, ev, ok)
+		} else {
+			m.alerts.line(line, ev, ok)
+		}
+		had := m.problemsHeight()
+		switch ev.Kind {
+		case events.Loop:
+			if m.stopAtLoop && !m.stopping {
+				m.stop()
+			}
+			cmds = append(cmds, m.printFinished())
+			m.iteration, m.phase = ev.Iteration, ev.Phase
+			m.waiting = false
+			m.problems.BeginIteration(ev.Iteration)
+			m.activity.BeginIteration(ev.Iteration, time.Now())
+			m.reloadTasks()
+		case events.Model:
+			m.model, m.turnStarted = ev.Model, time.Now()
+		case events.Waiting:
+			m.waiting = true
+		case events.Tool:
+			m.activity.Add(ev.Tool, ev.Title, time.Now())
+			if m.dogState != "happy" {
+				m.dogState = dogStateFor(m.activity.Mode())
+			}
+			if strings.Contains(ev.Title, tasks.File) {
+				m.reloadTasks()
+			}
+		}
+		m.problems.Feed(line)
+		if m.problemsHeight() != had {
+			m.resize()
+		}
+		// Runner lines end a tool block, so they are never folded away.
+		switch ev.Kind {
+		case events.Tool:
+			m.viewport.WriteToolHeader(line)
+		case "":
+			m.viewport.WriteLine(line)
+		default:
+			m.viewport.WriteNote(line)
+		}
+		// The runner may be gone by the time a line is handled.
+		if m.runner != nil {
+			cmds = append(cmds, m.runner.WaitForOutput())
+		}
+
+	case process.EditorDoneMsg:
+		m.reloadTasks()
+		if msg.Err != nil {
+			m.viewport.WriteNote(fmt.Sprintf("Editor failed for %s: %v", msg.Path, msg.Err))
+		}
+
+	case layoutSaveMsg:
+		if msg.seq == m.layoutSeq {
+			if err := m.saveLayout(); err != nil {
+				m.viewport.WriteNote(fmt.Sprintf("Error saving layout: %v", err))
+			}
+		}
+
+	case searchMsg:
+		if msg.seq == m.searchSeq && m.searchTyping {
+			m.runSearch()
+		}
+
+	case process.ShellReadyMsg:
+		cmds = append(cmds, m.enterShell(msg.Before))
+
+	case process.ShellExitedMsg:
+		cmds = append(cmds, process.DiffCmd(m.projectDir, msg))
+
+	case process.ShellDoneMsg:
+		m.logShellChanges(msg)
+		m.reloadTasks()
 
 	case process.DoneMsg:
+		m.activity.Finish(time.Now())
+		m.reloadTasks()
+		m.turnStarted = time.Time{}
+		m.run.Finish(msg.Err, time.Now())
+		m.alerts.finished(m.run.Outcome())
 		m.dogState = "sleeping"
-		m.viewport.WriteLine("\n--- Process Finished ---")
+		m.viewport.WriteNote("\n--- Process Finished ---")
 		if msg.Err != nil {
-			m.viewport.WriteLine(fmt.Sprintf("Error: %v", msg.Err))
+			m.viewport.WriteNote(fmt.Sprintf("Error: %v", msg.Err))
 			m.dogState = "barking"
 		}
-		m.runner = nil
+		cmds = append(cmds, m.printFinished())
+		m.runner, m.attached = nil, nil
+		m.stopping, m.stopAtLoop, m.waiting = false, false, false
+		if m.quitOnExit {
+			return m, tea.Sequence(tea.Batch(cmds...), tea.Quit)
+		}
+		if m.state == stateRunning || m.state == stateQuit {
+			m.state = stateDone
+		} else if m.prevState == stateRunning {
+			m.prevState = stateDone
+		}
 	}
 
-	// Handle Forms
-	if m.state == stateSetup {
-		form, cmd := m.form.Update(msg)
-		if f, ok := form.(*huh.Form); ok {
-			m.form = f
-			if m.form.State == huh.StateCompleted {
-				if m.selected == "new" {
-					m.state = stateRunning
-					cmds = append(cmds, m.newForm.Init())
-				} else {
-					m.state = stateRunning
-					cmds = append(cmds, m.startProcess())
+	// Handle Forms, unless the key already ran an action
+	if !handled {
+		if m.state == stateSettings {
+			m, cmd = m.updateSettings(msg)
+			cmds = append(cmds, cmd)
+		}
+		if m.state == stateHistory {
+			m, cmd = m.updateHistory(msg)
+			cmds = append(cmds, cmd)
+		}
+
+		if m.state == stateSetup {
+			form, cmd := m.form.Update(msg)
+			if f, ok := form.(*huh.Form); ok {
+				m.form = f
+				if m.form.State == huh.StateCompleted {
+					if m.selected == "new" {
+						m.state = stateRunning
+						cmds = append(cmds, m.newForm.Init())
+					} else {
+						m.state = stateRunning
+						cmds = append(cmds, m.startProcess())
+					}
 				}
 			}
+			cmds = append(cmds, cmd)
 		}
-		cmds = append(cmds, cmd)
-	}
 
-	if m.state == stateRunning && m.selected == "new" && m.runner == nil {
-		form, cmd := m.newForm.Update(msg)
-		if f, ok := form.(*huh.Form); ok {
-			m.newForm = f
-			if m.newForm.State == huh.StateCompleted {
-				m.args = append(m.args, "new", m.newIdea)
-				cmds = append(cmds, m.startProcess())
+		if m.state == stateRunning && m.selected == "new" && m.runner == nil {
+			form, cmd := m.newForm.Update(msg)
+			if f, ok := form.(*huh.Form); ok {
+				m.newForm = f
+				if m.newForm.State == huh.StateCompleted {
+					m.args = append(m.args, "new", m.newIdea)
+					cmds = append(cmds, m.startProcess())
+				}
 			}
+			cmds = append(cmds, cmd)
 		}
-		cmds = append(cmds, cmd)
 	}
-	
+
 	// Auto-advance splash
-	if m.state == stateSplash && m.frame > 100 {
+	if m.state == stateSplash && time.Since(m.started) > splashDuration {
 		m.state = stateSetup
 	}
 
-	// Update viewport
-	if m.ready {
+	// The header and footer change height as the search bar opens, help
+	// expands or the effect starts; keep the log fitted to what's left.
+	if m.ready && m.state == stateRunning {
+		m.viewport.SetSize(m.logSize())
+	}
+
+	// Update viewport, unless another pane has focus and takes the key
+	if k, ok := msg.(tea.KeyMsg); ok && (handled || m.focus != ui.PaneLog) {
+		if !handled {
+			m.updatePaneKeys(k)
+		}
+	} else if m.ready {
 		m.viewport, cmd = m.viewport.Update(msg)
 		cmds = append(cmds, cmd)
 	}
 
+	m.statusFile.update(m.status())
 	return m, tea.Batch(cmds...)
 }
 
-func (m *model) startProcess() tea.Cmd {
-	if !m.flags.ForceRun && !isatty.IsTerminal(os.Stdout.Fd()) {
-		m.viewport.WriteLine("Error: Not a TTY. Use --force-run.")
+// openShell snapshots the project, so changes can be reported on return,
+// then enterShell drops into $SHELL there.
+func (m *model) openShell() tea.Cmd {
+	m.viewport.WriteNote("--- Entering shell (exit to return) ---")
+	return process.SnapshotCmd(m.projectDir)
+}
+
+// enterShell suspends the TUI for $SHELL in the project dir.
+func (m *model) enterShell(before process.Snapshot) tea.Cmd {
+	return tea.ExecProcess(process.ShellCmd(m.projectDir), func(err error) tea.Msg {
+		return process.ShellExitedMsg{Before: before, Err: err}
+	})
+}
+
+// maxShellChanges limits how many changed files are listed individually.
+const maxShellChanges = 20
+
+func (m *model) logShellChanges(msg process.ShellDoneMsg) {
+	if msg.Err != nil {
+		m.viewport.WriteNote(fmt.Sprintf("Shell exited: %v", msg.Err))
+	}
+	if len(msg.Changes) == 0 {
+		m.viewport.WriteNote("--- Back from shell: no file changes ---")
+	} else {
+		m.viewport.WriteNote(fmt.Sprintf("--- Back from shell: %d file(s) changed ---", len(msg.Changes)))
+	}
+	if msg.Partial {
+		m.viewport.WriteNote("  (too many files to check them all; removals aren't listed)")
+	}
+	for i, c := range msg.Changes {
+		if i == maxShellChanges {
+			m.viewport.WriteNote(fmt.Sprintf("  ... and %d more", len(msg.Changes)-i))
+			break
+		}
+		m.viewport.WriteNote(fmt.Sprintf("  %-8s %s", c.Kind, c.Path))
+	}
+}
+
+// updateLinkMode handles keys while a file reference is being picked, keeping
+// them away from the viewport so arrows move the selection instead of scrolling.
+func (m model) updateLinkMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys().LinkNext):
+		m.viewport.MoveSelection(1)
+	case key.Matches(msg, m.keys().LinkPrev):
+		m.viewport.MoveSelection(-1)
+	case key.Matches(msg, m.keys().LinkExit):
+		m.viewport.ToggleLinkMode()
+	case key.Matches(msg, m.keys().LinkOpen):
+		ref, ok := m.viewport.SelectedRef()
+		if !ok {
+			return m, nil
+		}
+		m.viewport.ToggleLinkMode()
+		return m, tea.ExecProcess(process.EditorCmd(ref.Path, ref.Line, ref.Col), func(err error) tea.Msg {
+			return process.EditorDoneMsg{Path: ref.Path, Err: err}
+		})
+	case key.Matches(msg, m.keys().Quit):
+		m.viewport.ToggleLinkMode()
+		return m.Update(msg)
+	}
+	return m, nil
+}
+
+// splashDuration is how long the splash screen shows before setup.
+const splashDuration = 1500 * time.Millisecond
+
+// asciiOnly reports whether emoji and kaomoji should be avoided, either by
+// request or because the theme doesn't support them.
+func (m model) asciiOnly() bool {
+	return m.cfg.NoEmoji || !m.theme.SupportsEmoji
+}
+
+// effectPalette maps the theme onto a dark-to-bright ramp for sysc effects.
+func effectPalette(th theme.Theme) []string {
+	return []string{string(th.Background), string(th.Muted), string(th.Border), string(th.Accent), string(th.Highlight), string(th.Foreground)}
+}
+
+// loader renders the configured animation preset, repeated per its density
+// (doubled with --dense).
+func (m model) loader() string {
+	p := animations.Get(m.cfg.Anim)
+	if m.cfg.Quiet || len(p.Frames) == 0 {
+		return ""
+	}
+	frame, _ := animations.Frame(p, int(time.Since(m.started)/p.Interval))
+	if m.asciiOnly() {
+		frame = ui.StripEmoji(frame)
+	}
+	density := p.Density
+	if m.cfg.Dense {
+		density *= 2
+	}
+	return strings.Repeat(frame+" ", density)
+}
+
+func (m model) header() string {
+	heart := "♥"
+	if m.asciiOnly() {
+		heart = "<3"
+	}
+	word := persona.GetStatus(m.selected, m.snark)
+	if s := persona.ActivityStatus(string(m.activity.Mode()), m.snark); s != "" && m.runner != nil {
+		word = s
+	}
+	status := lipgloss.NewStyle().Foreground(m.theme.Highlight).Render(heart+" "+word+" "+heart+" "+m.spinner.View()) +
+		" " + lipgloss.NewStyle().Foreground(m.theme.AccentAlt).Render(m.loader())
+
+	lines := []string{}
+	if fx := m.motion.RenderEffect(); fx != "" {
+		lines = append(lines, fx)
+	}
+	lines = append(lines, status)
+	if m.state != stateRunning || !m.panes().Has(ui.PaneDog) {
+		lines = append(lines, m.dogView())
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// maxProblemRows caps the problems panel so the log keeps most of the screen.
+const maxProblemRows = 6
+
+func (m model) problemsHeight() int {
+	if !m.showProblems || m.problems.Len() == 0 {
+		return 0
+	}
+	return min(m.problems.Len()+1, maxProblemRows)
+}
+
+// dogStateFor lets the dog react to what the agent is up to.
+func dogStateFor(mode activity.Mode) string {
+	if mode == activity.Thrashing {
+		return "barking"
+	}
+	return "running"
+}
+
+func (m *model) resize() {
+	if m.width == 0 {
+		return
+	}
+	m.motion.SetEffect(m.cfg.FX, effectPalette(m.theme), m.width-4)
+	m.viewport.SetSize(m.logSize())
+	m.ready = true
+}
+
+// newViewport creates the log viewport over store. Only main gives the store
+// a session file, so tests don't write to the project.
+func (m model) newViewport(store *ui.LineStore) ui.LogViewport {
+	vp := ui.NewLogViewport(0, 0, store)
+	vp.Links = ui.NewRefFinder(m.projectDir)
+	vp.Theme = m.theme
+	vp.Palette = m.logPalette()
+	vp.Wrap = m.cfg.Log.Wrap
+	vp.Timestamps = m.cfg.Log.Timestamps
+	vp.CollapseAll(m.cfg.Log.CollapseTools)
+	return vp
+}
+
+// logPalette returns the colour remapping for runner output, if enabled.
+func (m model) logPalette() *ui.Palette {
+	if !m.cfg.Log.ThemeColors {
 		return nil
 	}
+	return ui.ThemePalette(m.theme)
+}
+
+// sessionLogPath names the file the full log of this session is kept in.
+func sessionLogPath(dir string, t time.Time) string {
+	return filepath.Join(dir, ".ralph", "tui", "session-"+t.Format("20060102-150405")+".log")
+}
 
+func (m *model) startProcess() tea.Cmd {
 	args := m.args
 	if m.selected == "watch" {
 		args = append([]string{"--watch"}, args...)
@@ -281,10 +729,7 @@ func (m *model) startProcess() tea.Cmd {
 	// Actually invoke the CLI (ralph.js -> ralph.sh mechanism, but we call 'vibepup' assuming it's in path or we call the shell script directly)
 	// For local dev, we might need to call the script directly if 'vibepup' isn't in PATH.
 	// But let's assume 'vibepup' is the command.
-	runCmd := "vibepup"
-	if m.flags.Runner != "" {
-		runCmd = m.flags.Runner
-	}
+	runCmd := runnerCommand(m.cfg)
 	
 	// If running locally from repo, we might want to call the script directly?
 	// The user said "run this project from the build".
@@ -292,11 +737,24 @@ func (m *model) startProcess() tea.Cmd {
 	// Let's use the first arg as the command if provided, or default to "vibepup"
 	
 	m.dogState = "running"
-	m.viewport.WriteLine("--- Starting Vibepup ---")
+	m.runStarted = time.Now()
+	m.viewport.WriteNote("--- Starting Vibepup ---")
 	
+	if m.cfg.Design {
+		args = append(args, "--design")
+	}
+	env := runnerEnv(m.cfg)
+
+	m.iterLimit = iterationLimit(args)
+	m.run = summary.New(args, m.runStarted)
+	m.tasksAtStart = m.tasks
 	var cmd tea.Cmd
-	m.runner, cmd = process.Start(context.Background(), runCmd, args)
-	
+	m.runner, cmd = process.Start(context.Background(), runCmd, args, env)
+	if m.runner == nil {
+		return cmd // it didn't start; cmd reports why
+	}
+	m.alerts.started(m.runStarted)
+
 	return tea.Batch(cmd, m.runner.WaitForOutput())
 }
 
@@ -304,6 +762,9 @@ func (m model) View() string {
 	if !m.ready {
 		return "Initializing..."
 	}
+	if m.paletteOpen {
+		return m.paletteView()
+	}
 
 	// 1. Splash
 	if m.state == stateSplash {
@@ -316,8 +777,24 @@ func (m model) View() string {
 		)
 	}
 
+	if m.state == stateSettings {
+		return ui.BoxStyle.Render(m.settingsView())
+	}
+	if m.state == stateHistory {
+		return ui.BoxStyle.Render(m.history.View())
+	}
+	if m.state == stateQuit {
+		return ui.BoxStyle.Render(m.quitForm.View())
+	}
+	if m.state == stateAddTask {
+		return ui.BoxStyle.Render(m.taskForm.View())
+	}
+	if m.state == stateDone {
+		return ui.BoxStyle.Render(m.doneView())
+	}
+
 	// 2. Form
-	if m.state == stateSetup || (m.state == stateRunning && m.runner == nil && m.selected == "new" && m.newForm.State != huh.StateCompleted) {
+	if m.state == stateSetup || m.askingIdea() {
 		form := m.form.View()
 		if m.selected == "new" {
 			form = m.newForm.View()
@@ -325,28 +802,117 @@ func (m model) View() string {
 		return ui.BoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
 			lipgloss.NewStyle().Foreground(m.theme.Accent).Render("SETUP"),
 			form,
+			m.help.View(m.helpKeys()),
 		))
 	}
 
 	// 3. Running
-	header := lipgloss.JoinVertical(lipgloss.Left,
-		lipgloss.NewStyle().Foreground(m.theme.Highlight).Render("♥ "+persona.GetStatus(m.selected, m.snark)+" ♥ "+m.spinner.View()),
-		motion.GetDogFrame(m.dogState, m.frame),
-		persona.RandomQuip(m.snark),
-	)
-
-	return ui.BoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
-		header,
-		m.viewport.View(),
-		m.help.View(m.keys),
-	))
+	if m.inline {
+		return m.inlineView()
+	}
+	return m.runningView()
 }
 
 func main() {
-	flags := config.Parse()
-	m := initialModel(flags)
-	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+	dir, _ := os.Getwd()
+	cfg, err := config.Load(dir, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		// The flag package has printed the usage already.
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "vibepup-tui: invalid configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if _, err := LoadKeymaps(cfg.Keys); err != nil {
+		fmt.Fprintln(os.Stderr, "vibepup-tui: invalid key bindings:")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if cfg.PrintConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+	if cfg.JSON {
+		os.Exit(runHeadless(os.Stdout, dir, cfg))
+	}
+	if cfg.StatusLine {
+		if err := statusLine(dir, cfg); err != nil {
+			fmt.Fprintln(os.Stderr, "vibepup-tui:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var command string
+	if len(cfg.Args) > 0 {
+		command = cfg.Args[0]
+	}
+	switch command {
+	case "daemon":
+		err = startDaemon(dir, cfg)
+	case "attach":
+		err = attachTUI(dir, cfg)
+	case "ctl":
+		err = ctl(dir, cfg.Args[1:])
+	case session.SuperviseCommand:
+		err = supervise(dir, cfg)
+	case session.AdoptCommand:
+		err = adopt(dir, cfg)
+	default:
+		// Nobody could see the TUI; print the run line by line instead.
+		if !cfg.ForceRun && !isatty.IsTerminal(os.Stdout.Fd()) {
+			os.Exit(runLinear(os.Stdout, dir, cfg))
+		}
+		m := initialModel(cfg)
+		store := ui.NewLineStore(cfg.Log.Scrollback, sessionLogPath(dir, time.Now()))
+		defer store.Close()
+		m.viewport = m.newViewport(store)
+		runTUI(m, cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "vibepup-tui:", err)
+		os.Exit(1)
+	}
+}
+
+// runTUI runs the program until the user quits.
+func runTUI(m model, cfg config.Config) {
+	var opts []tea.ProgramOption
+	if !cfg.NoAlt {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	ln, err := control.Listen(m.projectDir)
+	if err != nil {
+		// The TUI works without it, e.g. alongside another one.
+		m.viewport.WriteNote("Control socket unavailable: " + err.Error())
+	}
+	m.statusFile = &statusFile{dir: m.projectDir}
+	p := tea.NewProgram(m, opts...)
+	if ln != nil {
+		defer serveControl(ln, p)()
+	}
+	final, err := p.Run()
+	if fm, ok := final.(model); ok {
+		fm.statusFile.exit(fm)
+		fm.alerts.close()
+		if err := fm.saveLayout(); err != nil {
+			fmt.Fprintln(os.Stderr, "vibepup-tui: saving the layout:", err)
+		}
+		// Keep the iteration that was under way in the scrollback too.
+		if fm.inline {
+			for _, line := range fm.unprinted() {
+				fmt.Println(line)
+			}
+		}
+	}
+	if err != nil {
 		fmt.Println("Error:", err)
 		os.Exit(1)
 	}
+	if fm, ok := final.(model); ok && fm.detached != nil {
+		fmt.Printf("Runner left going (PID %d); its output goes to %s\n", fm.detached.PID, fm.detached.Log)
+		fmt.Println("Reattach with: vibepup-tui attach")
+	}
 }
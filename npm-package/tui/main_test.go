@@ -2,29 +2,60 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/x/exp/teatest"
 
 	"vibepup-tui/config"
+	"vibepup-tui/control"
+	"vibepup-tui/notify"
+	"vibepup-tui/process"
+	"vibepup-tui/session"
+	"vibepup-tui/summary"
+	"vibepup-tui/ui"
+	"vibepup-tui/webhook"
 )
 
 func waitForOutput(t *testing.T, tm *teatest.TestModel, needle []byte) {
+	var seen bytes.Buffer
 	deadline := time.Now().Add(2 * time.Second)
 	for time.Now().Before(deadline) {
-		if bytes.Contains(tm.Output(), needle) {
+		_, _ = io.Copy(&seen, tm.Output())
+		if bytes.Contains(seen.Bytes(), needle) {
 			return
 		}
 		time.Sleep(20 * time.Millisecond)
 	}
-	t.Fatalf("expected output to contain %q, got: %s", needle, string(tm.Output()))
+	t.Fatalf("expected output to contain %q, got: %s", needle, seen.String())
+}
+
+// writeRunner writes script as an executable runner in a temp dir and
+// returns its path.
+func writeRunner(t *testing.T, script string) string {
+	t.Helper()
+	runner := filepath.Join(t.TempDir(), "runner")
+	if err := os.WriteFile(runner, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return runner
 }
 
 func TestTUITransitionsToSetup(t *testing.T) {
-	flags := config.Flags{ForceRun: true}
-	m := initialModel(flags)
+	cfg := config.Config{ForceRun: true}
+	m := initialModel(cfg)
 
 	tm := teatest.NewTestModel(
 		t,
@@ -39,8 +70,8 @@ func TestTUITransitionsToSetup(t *testing.T) {
 }
 
 func TestTUIHelpToggle(t *testing.T) {
-	flags := config.Flags{ForceRun: true}
-	m := initialModel(flags)
+	cfg := config.Config{ForceRun: true}
+	m := initialModel(cfg)
 
 	tm := teatest.NewTestModel(
 		t,
@@ -56,3 +87,574 @@ func TestTUIHelpToggle(t *testing.T) {
 
 	waitForOutput(t, tm, []byte("help"))
 }
+
+func TestIterationLimit(t *testing.T) {
+	cases := []struct {
+		args []string
+		want int
+	}{
+		{nil, 5},
+		{[]string{"12"}, 12},
+		{[]string{"--watch", "--design"}, 0},
+		{[]string{"new", "an idea"}, 5},
+	}
+	for _, tc := range cases {
+		if got := iterationLimit(tc.args); got != tc.want {
+			t.Errorf("iterationLimit(%q) = %d, want %d", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestCommandPaletteRunsAction(t *testing.T) {
+	cfg := config.Config{ForceRun: true}
+	m := initialModel(cfg)
+
+	tm := teatest.NewTestModel(
+		t,
+		m,
+		teatest.WithInitialTermSize(80, 24),
+	)
+	defer tm.Quit()
+
+	tm.Send(tea.WindowSizeMsg{Width: 80, Height: 24})
+	waitForOutput(t, tm, []byte("SETUP"))
+
+	tm.Send(tea.KeyMsg{Type: tea.KeyCtrlP})
+	waitForOutput(t, tm, []byte("COMMANDS"))
+
+	tm.Type("settings")
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	waitForOutput(t, tm, []byte("Header effect"))
+}
+
+func TestQuitAsksWhileRunnerActive(t *testing.T) {
+	runner := writeRunner(t, "#!/bin/sh\necho started\nexec sleep 30\n")
+	m := initialModel(config.Config{ForceRun: true, Runner: runner})
+
+	tm := teatest.NewTestModel(
+		t,
+		m,
+		teatest.WithInitialTermSize(80, 24),
+	)
+	tm.Send(tea.WindowSizeMsg{Width: 80, Height: 24})
+	waitForOutput(t, tm, []byte("SETUP"))
+
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	waitForOutput(t, tm, []byte("started"))
+
+	tm.Type("q")
+	waitForOutput(t, tm, []byte("The runner is still going"))
+
+	// A second quit kills the runner rather than waiting on it.
+	tm.Type("q")
+	tm.WaitFinished(t, teatest.WithFinalTimeout(2*time.Second))
+}
+
+func TestControlSocketDrivesTheRunner(t *testing.T) {
+	dir := t.TempDir()
+	nudge := filepath.Join(dir, ".ralph", "nudge")
+	script := "#!/bin/sh\necho '🔁 Loop 1 (BUILD Phase)'\necho '⏸️  Project Complete. Waiting for changes in prd.md...'\n" +
+		"while [ ! -e " + nudge + " ]; do sleep 0.05; done\necho nudged\nexec sleep 30\n"
+	runner := writeRunner(t, script)
+	m := initialModel(config.Config{ForceRun: true, Runner: runner})
+	m.projectDir = dir
+
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 30))
+	tm.Send(tea.WindowSizeMsg{Width: 100, Height: 30})
+	waitForOutput(t, tm, []byte("SETUP"))
+
+	ln, err := control.Listen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go control.Serve(ln, func(req control.Request) control.Response {
+		reply := make(chan control.Response, 1)
+		tm.Send(controlMsg{req, reply})
+		return <-reply
+	})
+	defer ln.Close()
+	c, err := control.Dial(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.Do(control.Request{Cmd: "pause"}); err == nil || !strings.Contains(err.Error(), "setup screen") {
+		t.Errorf("pause before a run should be refused, got %v", err)
+	}
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	waitForOutput(t, tm, []byte("Waiting for changes"))
+
+	resp, err := c.Do(control.Request{Cmd: "status"})
+	if err != nil || !resp.Status.Running || !resp.Status.Waiting || resp.Status.Iteration != 1 {
+		t.Fatalf("status = %+v, %v", resp.Status, err)
+	}
+	if resp, err = c.Do(control.Request{Cmd: "append", Text: "Ship it"}); err != nil || resp.Status.Task != "Ship it" {
+		t.Errorf("append = %+v, %v", resp.Status, err)
+	}
+	if _, err := c.Do(control.Request{Cmd: "nudge"}); err != nil {
+		t.Fatalf("nudge: %v", err)
+	}
+	waitForOutput(t, tm, []byte("nudged"))
+	if resp, err = c.Do(control.Request{Cmd: "pause"}); err != nil || !resp.Status.Paused {
+		t.Errorf("pause = %+v, %v", resp.Status, err)
+	}
+	if _, err := c.Do(control.Request{Cmd: "paws"}); err == nil || !strings.Contains(err.Error(), `did you mean "pause"`) {
+		t.Errorf("a typo should get a suggestion, got %v", err)
+	}
+	if resp, err = c.Do(control.Request{Cmd: "stop"}); err != nil || !resp.Status.Stopping {
+		t.Errorf("stop = %+v, %v", resp.Status, err)
+	}
+	waitForOutput(t, tm, []byte("rerun"))
+	tm.Quit()
+}
+
+func TestStatusFileFollowsTheRun(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho '🔁 Loop 2 (BUILD Phase)'\necho '   Using: openai/gpt-5.2-codex'\nexec sleep 30\n"
+	runner := writeRunner(t, script)
+	m := initialModel(config.Config{ForceRun: true, Runner: runner})
+	m.projectDir = dir
+	m.statusFile = &statusFile{dir: dir}
+
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 30))
+	tm.Send(tea.WindowSizeMsg{Width: 100, Height: 30})
+	waitForOutput(t, tm, []byte("SETUP"))
+	if s, err := control.ReadStatus(dir); err != nil || s.Run != control.RunIdle {
+		t.Errorf("before the run: %+v, %v", s, err)
+	}
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	waitForOutput(t, tm, []byte("gpt-5.2-codex"))
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+	waitForOutput(t, tm, []byte("Runner paused"))
+
+	s, err := control.ReadStatus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Run != control.RunPaused || s.Iteration != 2 || s.Model != "openai/gpt-5.2-codex" || s.LastEvent != "model openai/gpt-5.2-codex" || s.Updated.IsZero() {
+		t.Errorf("status = %+v", s)
+	}
+	if got := s.Line(true); got != "vibepup paused | BUILD 2 | gpt-5.2-codex" {
+		t.Errorf("status line = %q", got)
+	}
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	waitForOutput(t, tm, []byte("The runner is still going"))
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	fm := tm.FinalModel(t, teatest.WithFinalTimeout(2*time.Second)).(model)
+	fm.statusFile.exit(fm)
+	if s, _ := control.ReadStatus(dir); s.Run != control.RunExited || s.Running {
+		t.Errorf("after quitting: %+v", s)
+	}
+}
+
+func TestHeadlessJSON(t *testing.T) {
+	dir := t.TempDir()
+	prd := filepath.Join(dir, "prd.md")
+	if err := os.WriteFile(prd, []byte("- [ ] one\n- [ ] two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+echo '🔁 Loop 1 (BUILD Phase)'
+echo '   Using: openai/gpt-5.2'
+echo './main.go:3:1: undefined: foo'
+echo '   ⚠️  Model openai/gpt-5.2 failed (Exit: 2). Falling back...'
+echo '❌ All models failed this iteration.'
+printf -- '- [x] one\n- [ ] two\n' > ` + prd + `
+echo '🔁 Loop 2 (BUILD Phase)'
+[ "$1" = fail ] && echo '❌ All models failed this iteration.' && exit 0
+echo '✅ Agent signaled completion.'
+`
+	runner := writeRunner(t, script)
+
+	var out bytes.Buffer
+	code := runHeadless(&out, dir, config.Config{Runner: runner})
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0 for a completed run", code)
+	}
+	var got []jsonEvent
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var ev jsonEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("not a JSON line: %q", line)
+		}
+		got = append(got, ev)
+	}
+	types := map[string]int{}
+	for _, ev := range got {
+		types[ev.Type]++
+	}
+	if types["start"] != 1 || types["loop"] != 2 || types["result"] != 1 || types["exhausted"] != 1 || types["problem"] != 1 || types["complete"] != 1 || types["output"] != 7 {
+		t.Errorf("event counts = %v", types)
+	}
+	if task := got[slices.IndexFunc(got, func(ev jsonEvent) bool { return ev.Type == "task" })]; task.Task != "one" {
+		t.Errorf("task event = %+v", task)
+	}
+	last := got[len(got)-1]
+	if last.Type != "exit" || last.Outcome != "complete" || *last.ExitCode != 0 || last.Iterations != 2 || *last.TasksDone != 1 {
+		t.Errorf("exit event = %+v", last)
+	}
+
+	out.Reset()
+	if code := runHeadless(&out, dir, config.Config{Runner: runner, Args: []string{"fail"}}); code != 4 {
+		t.Errorf("exit code = %d, want 4 when every model failed the last iteration", code)
+	}
+	if code := runHeadless(io.Discard, dir, config.Config{Runner: filepath.Join(dir, "missing")}); code != 127 {
+		t.Errorf("exit code = %d, want 127 for a missing runner", code)
+	}
+}
+
+func TestHeadlessPostsWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var got []webhook.Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(webhook.SignatureHeader) != webhook.Sign("hush", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var p webhook.Payload
+		json.Unmarshal(body, &p)
+		mu.Lock()
+		got = append(got, p)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	prd := filepath.Join(dir, "prd.md")
+	if err := os.WriteFile(prd, []byte("- [ ] one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+echo '🔁 Loop 1 (BUILD Phase)'
+echo '   Using: openai/gpt-5.2'
+echo '❌ All models failed this iteration.'
+echo '🔁 Loop 2 (BUILD Phase)'
+echo '   Using: openai/gpt-5.2'
+printf -- '- [x] one\n' > ` + prd + `
+echo '✅ Agent signaled completion.'
+`
+	runner := writeRunner(t, script)
+	cfg := config.Config{Runner: runner, Webhook: config.Webhook{URLs: srv.URL, Events: "started,exhausted,task_done,finished", Secret: "hush"}}
+	if code := runHeadless(io.Discard, dir, cfg); code != 0 {
+		t.Fatalf("exit code = %d", code)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var kinds []string
+	for _, p := range got {
+		kinds = append(kinds, p.Event)
+		if p.Project != dir || p.RunStarted.IsZero() || p.Time.Before(p.RunStarted) {
+			t.Errorf("payload %+v should name the project and be stamped", p)
+		}
+	}
+	// The PRD may be read before or after the exhausted line; the script
+	// runs ahead of the output.
+	if len(kinds) != 4 || kinds[0] != "started" || kinds[3] != "finished" {
+		t.Fatalf("posted %v, want started, exhausted, task_done and finished", kinds)
+	}
+	exhausted := got[slices.Index(kinds, "exhausted")]
+	task := got[slices.Index(kinds, "task_done")]
+	if exhausted.Iteration != 1 || exhausted.Model != "openai/gpt-5.2" || task.Task != "one" || got[3].Detail != "complete" {
+		t.Errorf("payloads = %+v", got)
+	}
+	if _, err := os.Stat(webhook.SpoolPath(dir)); !os.IsNotExist(err) {
+		t.Errorf("nothing should be spooled: %v", err)
+	}
+}
+
+func TestSupervisorPostsWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = append(got, r.Header.Get(webhook.EventHeader))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	runner := writeRunner(t, "#!/bin/sh\necho '🔁 Loop 1 (BUILD Phase)'\necho '❌ All models failed this iteration.'\n")
+	cfg := config.Config{Runner: runner, Args: []string{"__supervise"}, Log: config.Log{Scrollback: 100},
+		Webhook: config.Webhook{URLs: srv.URL, Events: "started,exhausted,finished"}}
+	if err := supervise(dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(got, []string{"started", "exhausted", "finished"}) {
+		t.Errorf("a run with no TUI attached posted %v", got)
+	}
+}
+
+func TestLinearOutput(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho '🔁 Loop 1 (BUILD Phase)'\nprintf '\\033[32m✅ tests pass\\033[0m\\n'\necho '✅ Agent signaled completion.'\n"
+	runner := writeRunner(t, script)
+	clock := func() time.Time { return time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local) }
+
+	var out bytes.Buffer
+	code := runPlain(dir, config.Config{Runner: runner}, &linearSink{w: &out, ascii: true, clock: clock})
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"12:00:00 | === Loop 1 · BUILD phase ===\n",
+		"12:00:00 | tests pass\n",
+		"=== Run finished: complete · 1 iteration(s)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "\x1b") || strings.Contains(got, "✅") {
+		t.Errorf("colour and emoji should be stripped:\n%q", got)
+	}
+
+	out.Reset()
+	runPlain(dir, config.Config{Runner: runner}, &linearSink{w: &out, color: true, clock: clock})
+	if !strings.Contains(out.String(), "\x1b[32m✅ tests pass") {
+		t.Errorf("the runner's colours should be kept with FORCE_COLOR:\n%q", out.String())
+	}
+
+	for _, tc := range []struct {
+		env  map[string]string
+		want bool
+	}{
+		{nil, false},
+		{map[string]string{"NO_COLOR": ""}, false},
+		{map[string]string{"FORCE_COLOR": "1"}, true},
+		{map[string]string{"FORCE_COLOR": "0"}, false},
+		{map[string]string{"FORCE_COLOR": "1", "NO_COLOR": "1"}, false},
+		{map[string]string{"FORCE_COLOR": "1", "NO_COLOR": ""}, true},
+	} {
+		if got := linearColor(func(k string) string { return tc.env[k] }); got != tc.want {
+			t.Errorf("colour with %v = %v, want %v", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestInlineModePrintsFinishedIterations(t *testing.T) {
+	script := "#!/bin/sh\necho '🔁 Loop 1 (BUILD Phase)'\necho first iteration output\necho '🔁 Loop 2 (BUILD Phase)'\necho second\nexec sleep 30\n"
+	runner := writeRunner(t, script)
+	m := initialModel(config.Config{ForceRun: true, NoAlt: true, Runner: runner, Log: config.Log{Scrollback: 100}})
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 40))
+	tm.Send(tea.WindowSizeMsg{Width: 100, Height: 40})
+	waitForOutput(t, tm, []byte("SETUP"))
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	waitForOutput(t, tm, []byte("first iteration output"))
+	waitForOutput(t, tm, []byte("second"))
+
+	// The first iteration is printed for good; the view holds the second.
+	// Quitting without killing the runner keeps its exit, which prints the
+	// rest, out of the final model.
+	if err := tm.Quit(); err != nil {
+		t.Fatal(err)
+	}
+	fm := tm.FinalModel(t, teatest.WithFinalTimeout(2*time.Second)).(model)
+	fm.runner.Kill()
+	store := fm.viewport.Store()
+	if fm.printed == 0 || !strings.Contains(store.Line(fm.printed), "Loop 2") {
+		t.Errorf("printed up to line %d (%q), want up to the second loop", fm.printed, store.Line(fm.printed))
+	}
+	view := fm.inlineView()
+	if n := strings.Count(view, "\n") + 1; n != inlineLines+2 {
+		t.Errorf("inline view is %d rows, want %d:\n%s", n, inlineLines+2, view)
+	}
+	if strings.Contains(view, "first iteration output") || !strings.Contains(view, "second") {
+		t.Errorf("inline view should only show the current iteration:\n%s", view)
+	}
+}
+
+func TestAttachReplaysScrollback(t *testing.T) {
+	dir := t.TempDir()
+	runner, done := process.Start(context.Background(), "sh", []string{"-c", "echo '🔁 Loop 2 (BUILD Phase)'; echo '❌ All models failed this iteration.'; echo replayed line; exec sleep 30"}, nil)
+	if runner == nil {
+		t.Fatalf("runner didn't start: %v", done())
+	}
+	defer runner.Kill()
+	store := ui.NewLineStore(100, "")
+	meta := session.Meta{PID: runner.Pid(), Args: []string{"--watch"}, Started: time.Now()}
+	ln, err := session.Listen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sup := &session.Supervisor{Dir: dir, Meta: meta, Runner: runner, Store: store, Replay: 100}
+	go sup.Serve(ln, done)
+	// Wait for the lines to be out, so the TUI gets them replayed.
+	probe, err := session.Dial(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := probe.Runner()
+	for range 3 {
+		<-out.OutputChan
+	}
+	probe.Close()
+
+	c, err := session.Dial(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(config.Config{ForceRun: true})
+	var bells bytes.Buffer
+	m.alerts.notifier.Term = &bells
+	m.alerts.notifier.Routes[notify.Exhausted] = []notify.Channel{notify.Bell}
+	m.startup = m.attach(c)
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 30))
+	tm.Send(tea.WindowSizeMsg{Width: 100, Height: 30})
+	waitForOutput(t, tm, []byte("replayed line"))
+	tm.Quit()
+	if m := tm.FinalModel(t, teatest.WithFinalTimeout(time.Second)); m.(model).iteration != 2 {
+		t.Errorf("iteration = %d, want 2 from the replayed banner", m.(model).iteration)
+	}
+	if bells.Len() != 0 {
+		t.Errorf("replayed lines notified: %q", bells.String())
+	}
+}
+
+func TestOutputAfterExitIsHandled(t *testing.T) {
+	m := initialModel(config.Config{ForceRun: true, Log: config.Log{Scrollback: 100}})
+	m.runner, _ = process.Start(context.Background(), "true", nil, nil)
+	next, _ := m.Update(process.DoneMsg{})
+	next, cmd := next.(model).Update(process.OutputMsg("late line"))
+	// Run what the line asks for, as Bubble Tea would.
+	var run func(tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		if batch, ok := cmd().(tea.BatchMsg); ok {
+			for _, c := range batch {
+				run(c)
+			}
+		}
+	}
+	run(cmd)
+	vp := next.(model).viewport
+	if store := vp.Store(); store.Line(store.Total()-1) != "late line" {
+		t.Errorf("last line = %q", store.Line(store.Total()-1))
+	}
+}
+
+func TestCompletionPrintedLastCounts(t *testing.T) {
+	// A runner that exits straight after its completion line: the line is
+	// handled before the exit is.
+	script := "#!/bin/sh\necho '🔁 Loop 1 (BUILD Phase)'\nseq 1 300\necho '✅ Agent signaled completion.'\n"
+	runner := writeRunner(t, script)
+	m := initialModel(config.Config{ForceRun: true, Runner: runner, Log: config.Log{Scrollback: 100}})
+	m.quitOnExit = true
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 30))
+	tm.Send(tea.WindowSizeMsg{Width: 100, Height: 30})
+	waitForOutput(t, tm, []byte("SETUP"))
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	fm := tm.FinalModel(t, teatest.WithFinalTimeout(10*time.Second)).(model)
+	if got := fm.run.Outcome(); got != summary.OutcomeComplete {
+		t.Errorf("outcome = %q, want %q", got, summary.OutcomeComplete)
+	}
+}
+
+func TestLayoutIsSavedOnceItSettles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := initialModel(config.Config{ForceRun: true})
+	if m.switchPreset() == nil || m.switchPreset() == nil {
+		t.Fatal("a preset switch should schedule a save")
+	}
+	next, _ := m.Update(layoutSaveMsg{seq: m.layoutSeq - 1})
+	m = next.(model)
+	if _, err := os.Stat(config.UserPath()); !os.IsNotExist(err) {
+		t.Fatalf("saved before the layout settled: %v", err)
+	}
+	next, _ = m.Update(layoutSaveMsg{seq: m.layoutSeq})
+	m = next.(model)
+	data, err := os.ReadFile(config.UserPath())
+	if err != nil || !strings.Contains(string(data), m.arrangement.Name) {
+		t.Fatalf("user config = %q, %v; want preset %q", data, err, m.arrangement.Name)
+	}
+	if err := os.Remove(config.UserPath()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.saveLayout(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(config.UserPath()); !os.IsNotExist(err) {
+		t.Errorf("an unchanged layout shouldn't be saved again on exit: %v", err)
+	}
+
+	// A layout the project sets isn't the user's to save.
+	cfg := config.Config{ForceRun: true}
+	if err := cfg.Set("layout.preset", "zen", config.FileSource("project", ".vibepup.toml")); err != nil {
+		t.Fatal(err)
+	}
+	m = initialModel(cfg)
+	if m.switchPreset() != nil {
+		t.Error("a layout from .vibepup.toml shouldn't be saved")
+	}
+	if err := m.saveLayout(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(config.UserPath()); !os.IsNotExist(err) {
+		t.Errorf("the user config was written: %v", err)
+	}
+}
+
+func TestDefaultKeymapsHaveNoConflicts(t *testing.T) {
+	if _, err := LoadKeymaps(nil); err != nil {
+		t.Fatalf("default bindings: %v", err)
+	}
+}
+
+func TestLoadKeymaps(t *testing.T) {
+	km, err := LoadKeymaps(map[string][]string{
+		"quit":         {"ctrl+q", "ctrl+c"},
+		"running.quit": {"ctrl+c"},
+		"pet":          {"space"},
+		"log.live":     {},
+	})
+	if err != nil {
+		t.Fatalf("LoadKeymaps: %v", err)
+	}
+	if got := km.For(stateSetup).Quit.Keys(); len(got) != 2 || got[0] != "ctrl+q" {
+		t.Errorf("setup quit keys = %q", got)
+	}
+	if got := km.For(stateRunning).Quit.Keys(); len(got) != 1 || got[0] != "ctrl+c" {
+		t.Errorf("running quit keys = %q", got)
+	}
+	running := km.For(stateRunning)
+	if !key.Matches(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}, running.Pet) {
+		t.Error("space should pet the dog")
+	}
+	if h := running.Pet.Help(); h.Key != "space" || h.Desc != "pet dog" {
+		t.Errorf("pet help = %+v", h)
+	}
+	if running.Live.Enabled() {
+		t.Error("an empty key list should unbind log.live")
+	}
+
+	for _, tc := range []struct {
+		overrides map[string][]string
+		want      string
+	}{
+		{map[string][]string{"qiut": {"x"}}, `did you mean "quit"`},
+		{map[string][]string{"quit": {"ctrl+Q"}}, `unknown key "ctrl+Q"`},
+		{map[string][]string{"quit": {"backspace"}}, ""},
+		{map[string][]string{"log.wrap": {"G"}}, `"G" is bound to both log.live and log.wrap`},
+		{map[string][]string{"done.log.wrap": {"x"}}, "not used on the done screen"},
+		{map[string][]string{"running.run.start": {"x"}}, "not used on the running screen"},
+	} {
+		_, err := LoadKeymaps(tc.overrides)
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", tc.overrides, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: expected an error containing %q, got %v", tc.overrides, tc.want, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package motion
 
 import (
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -9,10 +10,25 @@ import (
 
 type TickMsg time.Time
 
+// HeaderEffect is the subset of sysc-Go effects the header needs; not every
+// effect implements the full animations.Animation interface.
+type HeaderEffect interface {
+	Update()
+	Render() string
+}
+
 type Engine struct {
 	Quiet   bool
 	LowPerf bool
-	Effect  animations.Animation
+	Effect  HeaderEffect
+}
+
+// EffectHeight is the number of rows the header effect occupies.
+const EffectHeight = 3
+
+// Effects lists the sysc header effects selectable with --fx.
+func Effects() []string {
+	return []string{"fire", "matrix", "none"}
 }
 
 func New(lowPerf, quiet bool) Engine {
@@ -25,11 +41,44 @@ func New(lowPerf, quiet bool) Engine {
 	}
 }
 
+// SetEffect (re)creates the header effect at the given width. Quiet and
+// low-performance modes skip effects entirely.
+func (e *Engine) SetEffect(name string, palette []string, width int) {
+	e.Effect = nil
+	if e.Quiet || e.LowPerf || width <= 0 {
+		return
+	}
+	switch name {
+	case "fire":
+		e.Effect = animations.NewFireEffect(width, EffectHeight, palette)
+	case "matrix":
+		e.Effect = animations.NewMatrixEffect(width, EffectHeight, palette)
+	}
+}
+
+// Step advances the header effect by one frame.
+func (e Engine) Step() {
+	if e.Effect != nil {
+		e.Effect.Update()
+	}
+}
+
+// RenderEffect returns the current header effect frame, or "" if none.
+func (e Engine) RenderEffect() string {
+	if e.Effect == nil {
+		return ""
+	}
+	return strings.TrimRight(e.Effect.Render(), "\n")
+}
+
 func (e Engine) Next() tea.Cmd {
 	d := time.Millisecond * 1000 / 60
 	if e.LowPerf {
 		d = time.Millisecond * 1000 / 15
 	}
+	if e.Quiet {
+		d = time.Millisecond * 1000 / 10
+	}
 	return tea.Tick(d, func(t time.Time) tea.Msg {
 		return TickMsg(t)
 	})
@@ -84,3 +133,26 @@ func GetDogFrame(state string, frame int) string {
 		return "            \n" + frames[(frame/10)%len(frames)]
 	}
 }
+
+// GetASCIIDogFrame is GetDogFrame for terminals (or users) without emoji and
+// kaomoji support.
+func GetASCIIDogFrame(state string, frame int) string {
+	switch state {
+	case "sleeping":
+		zzz := []string{"", "z", "zz", "zzz"}
+		return "      " + zzz[(frame/30)%4] + "\n" + "(-.-)"
+	case "barking":
+		if (frame/10)%2 == 0 {
+			return "    WOOF!   \n" + "(>o<)"
+		}
+		return "            \n" + "(>o<)"
+	case "happy":
+		if (frame/5)%2 == 0 {
+			return "            \n" + "(^o^)/"
+		}
+		return "            \n" + "(^.^)"
+	default:
+		frames := []string{"(o.o)", "(O.O)"}
+		return "            \n" + frames[(frame/10)%len(frames)]
+	}
+}
@@ -0,0 +1,320 @@
+// Package notify tells the user about the moments of a long run worth
+// looking up for: a task done, the PRD finished, every model failing, the
+// watchdog killing a turn and the agent stuck at a prompt. Notifications
+// go out as the terminal bell, OSC 9 or OSC 777 escape sequences, or
+// through notify-send.
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"vibepup-tui/events"
+)
+
+// Kind is a kind of run event. Started, Iteration and Finished only mark
+// the run's progress, for webhooks; the others are worth a notification.
+type Kind string
+
+const (
+	// Started is the runner starting.
+	Started Kind = "started"
+	// Iteration is a new loop of the runner.
+	Iteration Kind = "iteration"
+	// TaskDone is a task checked off in the PRD.
+	TaskDone Kind = "task_done"
+	// AllDone is the last task checked off, or the agent reporting the PRD
+	// done.
+	AllDone Kind = "all_done"
+	// Exhausted is every model in the chain failing an iteration.
+	Exhausted Kind = "exhausted"
+	// Watchdog is the runner's watchdog killing a turn.
+	Watchdog Kind = "watchdog"
+	// Prompt is the agent printing what looks like a question for the
+	// user. Nobody can answer it, so the turn is stuck until the watchdog
+	// kills it.
+	Prompt Kind = "prompt"
+	// Finished is the runner exiting; Detail is the outcome.
+	Finished Kind = "finished"
+)
+
+// Kinds lists every kind of event.
+func Kinds() []Kind {
+	return []Kind{Started, Iteration, TaskDone, AllDone, Exhausted, Watchdog, Prompt, Finished}
+}
+
+// Event is one moment of a run.
+type Event struct {
+	Kind      Kind
+	Project   string // the project directory
+	Iteration int
+	Model     string
+	Task      string
+	// Detail is the iteration's phase, the watchdog's reason ("timeout" or
+	// "no_output"), the line that looked like a prompt or the run's
+	// outcome.
+	Detail string
+	Time   time.Time
+	// Started is when the run started.
+	Started time.Time
+}
+
+// Title is the event in a few words, e.g. "Task done".
+func (e Event) Title() string {
+	switch e.Kind {
+	case Started:
+		return "Run started"
+	case Iteration:
+		return fmt.Sprintf("Iteration %d", e.Iteration)
+	case TaskDone:
+		return "Task done"
+	case AllDone:
+		return "All tasks complete"
+	case Exhausted:
+		return "Every model failed"
+	case Watchdog:
+		return "Turn killed by the watchdog"
+	case Prompt:
+		return "The agent is waiting for input"
+	case Finished:
+		return "Run finished"
+	}
+	return string(e.Kind)
+}
+
+// Message is the event in a sentence.
+func (e Event) Message() string {
+	switch e.Kind {
+	case Iteration:
+		return e.Detail + " phase"
+	case TaskDone:
+		return e.Task
+	case AllDone:
+		return "Every task in the PRD is checked off."
+	case Exhausted:
+		return fmt.Sprintf("Iteration %d: every model in the chain failed.", e.Iteration)
+	case Watchdog:
+		why := "ran too long"
+		if e.Detail == events.NoOutput {
+			why = "went silent"
+		}
+		if e.Model == "" {
+			return fmt.Sprintf("Iteration %d: the turn %s.", e.Iteration, why)
+		}
+		return fmt.Sprintf("Iteration %d: the turn with %s %s.", e.Iteration, e.Model, why)
+	case Prompt, Finished:
+		return e.Detail
+	}
+	return ""
+}
+
+// Channel is a way of notifying.
+type Channel string
+
+const (
+	// Bell rings the terminal bell.
+	Bell Channel = "bell"
+	// OSC9 is the notification sequence of iTerm2, WezTerm, Windows
+	// Terminal, kitty and Ghostty.
+	OSC9 Channel = "osc9"
+	// OSC777 is the notification sequence of rxvt-unicode, foot and
+	// VTE-based terminals.
+	OSC777 Channel = "osc777"
+	// NotifySend is a desktop notification through notify-send.
+	NotifySend Channel = "notify-send"
+)
+
+// Channels lists every channel.
+func Channels() []Channel {
+	return []Channel{Bell, OSC9, OSC777, NotifySend}
+}
+
+// ParseChannels reads a comma-separated channel list such as
+// "bell,notify-send". An empty list or "none" turns notifications off.
+func ParseChannels(s string) ([]Channel, error) {
+	var out []Channel
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "none" {
+			continue
+		}
+		known := false
+		for _, c := range Channels() {
+			known = known || c == Channel(name)
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown channel %q", name)
+		}
+		out = append(out, Channel(name))
+	}
+	return out, nil
+}
+
+// Notifier sends events down the channels configured for their kind.
+type Notifier struct {
+	// Routes lists the channels for each kind; kinds without any don't
+	// notify.
+	Routes map[Kind][]Channel
+	// Every is the least time between two notifications of one kind, so a
+	// flapping model doesn't spam. Events in between are held back; the
+	// latest goes out when the time is up, counting the others.
+	Every time.Duration
+	// Term receives the bell and the OSC sequences; nil when it isn't a
+	// terminal.
+	Term io.Writer
+	// Tmux wraps the OSC sequences so tmux passes them through.
+	Tmux bool
+	// Desktop shows a desktop notification; nil when notify-send isn't
+	// installed.
+	Desktop func(title, body string) error
+
+	now   func() time.Time
+	after func(time.Duration, func()) // time.AfterFunc, but for tests
+
+	mu      sync.Mutex // the held events go out from a timer
+	last    map[Kind]time.Time
+	held    map[Kind]int
+	pending map[Kind]Event // the latest event held back, with a flush due
+}
+
+// Send notifies of each event whose kind has channels, unless one of the
+// same kind went out too recently.
+func (n *Notifier) Send(evs ...Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ev := range evs {
+		n.send(ev)
+	}
+}
+
+func (n *Notifier) send(ev Event) {
+	if len(n.Routes[ev.Kind]) == 0 {
+		return
+	}
+	now := n.clock()
+	if n.last == nil {
+		n.last, n.held, n.pending = map[Kind]time.Time{}, map[Kind]int{}, map[Kind]Event{}
+	}
+	if last, ok := n.last[ev.Kind]; ok && now.Sub(last) < n.Every {
+		n.hold(ev, last.Add(n.Every).Sub(now))
+		return
+	}
+	n.notify(ev, now)
+}
+
+func (n *Notifier) clock() time.Time {
+	if n.now != nil {
+		return n.now()
+	}
+	return time.Now()
+}
+
+// hold keeps ev back, to go out in wait unless another of its kind does
+// first.
+func (n *Notifier) hold(ev Event, wait time.Duration) {
+	n.held[ev.Kind]++
+	if _, due := n.pending[ev.Kind]; !due {
+		after := n.after
+		if after == nil {
+			after = func(d time.Duration, f func()) { time.AfterFunc(d, f) }
+		}
+		after(wait, func() { n.flush(ev.Kind) })
+	}
+	n.pending[ev.Kind] = ev
+}
+
+// flush sends the latest event of kind held back, counting the others.
+func (n *Notifier) flush(kind Kind) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ev, ok := n.pending[kind]
+	if !ok {
+		return
+	}
+	n.held[kind]--
+	n.notify(ev, n.clock())
+}
+
+// notify sends ev down its channels.
+func (n *Notifier) notify(ev Event, now time.Time) {
+	channels := n.Routes[ev.Kind]
+	n.last[ev.Kind] = now
+	delete(n.pending, ev.Kind)
+
+	title := "vibepup"
+	if ev.Project != "" {
+		title += " · " + filepath.Base(ev.Project)
+	}
+	body := ev.Title()
+	if msg := ev.Message(); msg != "" {
+		body += ": " + msg
+	}
+	if held := n.held[ev.Kind]; held > 0 {
+		body += fmt.Sprintf(" (and %d more)", held)
+		n.held[ev.Kind] = 0
+	}
+	title, body = clean(title), clean(body)
+
+	for _, c := range channels {
+		switch c {
+		case Bell:
+			n.write("\a")
+		case OSC9:
+			n.write(n.osc("9;" + title + ": " + body))
+		case OSC777:
+			n.write(n.osc("777;notify;" + strings.ReplaceAll(title, ";", ",") + ";" + body))
+		case NotifySend:
+			if n.Desktop != nil {
+				_ = n.Desktop(title, body)
+			}
+		}
+	}
+}
+
+func (n *Notifier) write(s string) {
+	if n.Term != nil {
+		_, _ = io.WriteString(n.Term, s)
+	}
+}
+
+// osc builds an OSC sequence, wrapped for tmux if need be.
+func (n *Notifier) osc(payload string) string {
+	seq := "\x1b]" + payload + "\a"
+	if n.Tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// clean drops control characters, which would end an OSC sequence early
+// or garble the terminal.
+func clean(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// NotifySendCommand returns a Desktop func that runs notify-send without
+// waiting for it, or nil when it isn't installed.
+func NotifySendCommand() func(title, body string) error {
+	path, err := exec.LookPath("notify-send")
+	if err != nil {
+		return nil
+	}
+	return func(title, body string) error {
+		cmd := exec.Command(path, "--app-name=vibepup", title, body)
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		go cmd.Wait()
+		return nil
+	}
+}
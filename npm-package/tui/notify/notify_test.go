@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifierChannels(t *testing.T) {
+	var term bytes.Buffer
+	var desktop []string
+	n := &Notifier{
+		Routes: map[Kind][]Channel{
+			TaskDone:  {OSC9},
+			Exhausted: {Bell, OSC777, NotifySend},
+		},
+		Term:    &term,
+		Desktop: func(title, body string) error { desktop = append(desktop, title+" | "+body); return nil },
+	}
+	n.Send(
+		Event{Kind: TaskDone, Project: "/work/app", Task: "Write the\x1b docs"},
+		Event{Kind: Exhausted, Project: "/work/app", Iteration: 3},
+		Event{Kind: Watchdog, Project: "/work/app"}, // no channels
+	)
+	want := "\x1b]9;vibepup · app: Task done: Write the docs\a" +
+		"\a\x1b]777;notify;vibepup · app;Every model failed: Iteration 3: every model in the chain failed.\a"
+	if term.String() != want {
+		t.Errorf("terminal got %q\nwant %q", term.String(), want)
+	}
+	if len(desktop) != 1 || desktop[0] != "vibepup · app | Every model failed: Iteration 3: every model in the chain failed." {
+		t.Errorf("desktop got %q", desktop)
+	}
+
+	term.Reset()
+	n.Tmux = true
+	n.Routes[Prompt] = []Channel{OSC9}
+	n.Send(Event{Kind: Prompt, Detail: "Continue? [y/N]"})
+	if want := "\x1bPtmux;\x1b\x1b]9;vibepup: The agent is waiting for input: Continue? [y/N]\a\x1b\\"; term.String() != want {
+		t.Errorf("under tmux got %q, want %q", term.String(), want)
+	}
+}
+
+func TestNotifierRateLimits(t *testing.T) {
+	var term bytes.Buffer
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	n := &Notifier{
+		Routes: map[Kind][]Channel{Exhausted: {OSC9}, AllDone: {OSC9}},
+		Every:  time.Minute,
+		Term:   &term,
+		now:    func() time.Time { return now },
+	}
+	sent := func() int { return strings.Count(term.String(), "\x1b]9;") }
+
+	n.Send(Event{Kind: Exhausted, Iteration: 1})
+	now = now.Add(20 * time.Second)
+	n.Send(Event{Kind: Exhausted, Iteration: 2}, Event{Kind: Exhausted, Iteration: 3})
+	if sent() != 1 {
+		t.Fatalf("a flapping model should notify once a minute, got %q", term.String())
+	}
+	// Other kinds have their own limit.
+	n.Send(Event{Kind: AllDone})
+	if sent() != 2 {
+		t.Fatalf("all done was held back by another kind: %q", term.String())
+	}
+	now = now.Add(time.Minute)
+	term.Reset()
+	n.Send(Event{Kind: Exhausted, Iteration: 4})
+	if !strings.Contains(term.String(), "Iteration 4: every model in the chain failed. (and 2 more)") {
+		t.Errorf("the next notification should count those held back, got %q", term.String())
+	}
+}
+
+func TestNotifierFlushesHeldEvents(t *testing.T) {
+	var term bytes.Buffer
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var due []time.Time
+	var flushes []func()
+	n := &Notifier{
+		Routes: map[Kind][]Channel{Watchdog: {OSC9}},
+		Every:  time.Minute,
+		Term:   &term,
+		now:    func() time.Time { return now },
+		after: func(d time.Duration, f func()) {
+			due, flushes = append(due, now.Add(d)), append(flushes, f)
+		},
+	}
+
+	n.Send(Event{Kind: Watchdog, Iteration: 1})
+	now = now.Add(10 * time.Second)
+	n.Send(Event{Kind: Watchdog, Iteration: 2})
+	now = now.Add(10 * time.Second)
+	n.Send(Event{Kind: Watchdog, Iteration: 3})
+	if len(flushes) != 1 || !due[0].Equal(now.Add(40*time.Second)) {
+		t.Fatalf("one flush should be due when the minute is up, got %v", due)
+	}
+
+	// The burst is over: the latest held event goes out when the time is up.
+	now = due[0]
+	term.Reset()
+	flushes[0]()
+	if !strings.Contains(term.String(), "Iteration 3: the turn ran too long. (and 1 more)") {
+		t.Errorf("the held events should go out at the end of the window, got %q", term.String())
+	}
+	term.Reset()
+	flushes[0]()
+	if term.Len() != 0 {
+		t.Errorf("a flush should send once, got %q", term.String())
+	}
+
+	// An event that goes out first takes the held ones along.
+	now = now.Add(10 * time.Second)
+	n.Send(Event{Kind: Watchdog, Iteration: 4})
+	now = now.Add(time.Minute)
+	n.Send(Event{Kind: Watchdog, Iteration: 5})
+	term.Reset()
+	flushes[1]()
+	if term.Len() != 0 || len(flushes) != 2 {
+		t.Errorf("a flush after the held event went out should do nothing, got %q", term.String())
+	}
+}
+
+func TestParseChannels(t *testing.T) {
+	got, err := ParseChannels(" bell, notify-send ,")
+	if err != nil || len(got) != 2 || got[0] != Bell || got[1] != NotifySend {
+		t.Errorf("ParseChannels = %v, %v", got, err)
+	}
+	if got, err := ParseChannels("none"); err != nil || len(got) != 0 {
+		t.Errorf("none = %v, %v", got, err)
+	}
+	if _, err := ParseChannels("bell,osc8"); err == nil {
+		t.Error("osc8 should be unknown")
+	}
+}
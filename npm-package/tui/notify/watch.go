@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"vibepup-tui/events"
+	"vibepup-tui/tasks"
+)
+
+// promptLine matches output that asks the user something: a yes/no
+// question, a password or an "any key" pause.
+var promptLine = regexp.MustCompile(`(?i)[\[(](y/n|yes/no)[\])]|press (enter|return|any key)|(password|passphrase)[^:]*:\s*$`)
+
+// Watcher picks the events of a run out of its output and the PRD.
+type Watcher struct {
+	project   string
+	started   time.Time
+	iteration int
+	model     string
+	tasks     tasks.List
+	// allDone keeps AllDone from repeating until a task is unchecked
+	// again.
+	allDone bool
+	now     func() time.Time
+}
+
+// NewWatcher watches a run in project, whose PRD starts out as list.
+func NewWatcher(project string, list tasks.List) *Watcher {
+	return &Watcher{project: project, tasks: list, allDone: allDone(list), now: time.Now}
+}
+
+// Start returns the event of the runner starting at at.
+func (w *Watcher) Start(at time.Time) Event {
+	w.started, w.iteration, w.model = at, 0, ""
+	return w.event(Started, "")
+}
+
+// Finish returns the event of the runner exiting with outcome, one of the
+// summary.Outcome* values.
+func (w *Watcher) Finish(outcome string) Event {
+	return w.event(Finished, outcome)
+}
+
+// Line returns the events an output line brings, given the runner event
+// parsed from it, if any.
+func (w *Watcher) Line(line string, ev events.Event, ok bool) []Event {
+	if !ok {
+		text := strings.TrimSpace(ansi.Strip(line))
+		if promptLine.MatchString(text) {
+			return []Event{w.event(Prompt, text)}
+		}
+		return nil
+	}
+	switch ev.Kind {
+	case events.Loop:
+		w.iteration, w.model = ev.Iteration, ""
+		return []Event{w.event(Iteration, ev.Phase)}
+	case events.Model:
+		w.model = ev.Model
+	case events.Exhausted:
+		return []Event{w.event(Exhausted, "")}
+	case events.Watchdog:
+		return []Event{w.event(Watchdog, ev.Result)}
+	case events.Complete:
+		if !w.allDone {
+			w.allDone = true
+			return []Event{w.event(AllDone, "")}
+		}
+	}
+	return nil
+}
+
+// Tasks returns the events of the PRD changing to list: a TaskDone for
+// each task checked off since the last call, and AllDone once the last
+// one is.
+func (w *Watcher) Tasks(list tasks.List) []Event {
+	var out []Event
+	for _, t := range list.CompletedSince(w.tasks) {
+		ev := w.event(TaskDone, "")
+		ev.Task = t.Text
+		out = append(out, ev)
+	}
+	w.tasks = list
+	switch {
+	case !allDone(list):
+		w.allDone = false
+	case !w.allDone:
+		w.allDone = true
+		out = append(out, w.event(AllDone, ""))
+	}
+	return out
+}
+
+// event stamps an event with where the run is; Task is the task being
+// worked on.
+func (w *Watcher) event(kind Kind, detail string) Event {
+	ev := Event{Kind: kind, Project: w.project, Iteration: w.iteration, Model: w.model, Detail: detail, Time: w.now(), Started: w.started}
+	if t, ok := w.tasks.Next(); ok {
+		ev.Task = t.Text
+	}
+	return ev
+}
+
+func allDone(list tasks.List) bool {
+	return list.Total() > 0 && list.Done() == list.Total()
+}
@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"vibepup-tui/events"
+	"vibepup-tui/tasks"
+)
+
+func TestWatcherLines(t *testing.T) {
+	w := NewWatcher("/work/app", tasks.Parse(strings.NewReader("- [x] Set up\n- [ ] Add login\n")))
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := []Event{w.Start(started)}
+	for _, line := range []string{
+		"🔁 Loop 2 (BUILD Phase)",
+		"   Using: openai/gpt-5.2",
+		"Overwrite existing config? (y/n)",
+		"[RALPH] NO OUTPUT: likely waiting for input / hung tool",
+		"   ⚠️  Model openai/gpt-5.2 failed (Exit: 1). Falling back...",
+		"Ask me anything later",
+		"❌ All models failed this iteration.",
+		"✅ Agent signaled completion.",
+		"✅ Agent signaled completion.",
+	} {
+		ev, ok := events.Parse(line)
+		got = append(got, w.Line(line, ev, ok)...)
+	}
+	got = append(got, w.Finish("complete"))
+	want := []Event{
+		{Kind: Started, Task: "Add login"},
+		{Kind: Iteration, Iteration: 2, Task: "Add login", Detail: "BUILD"},
+		{Kind: Prompt, Iteration: 2, Model: "openai/gpt-5.2", Task: "Add login", Detail: "Overwrite existing config? (y/n)"},
+		{Kind: Watchdog, Iteration: 2, Model: "openai/gpt-5.2", Task: "Add login", Detail: events.NoOutput},
+		{Kind: Exhausted, Iteration: 2, Model: "openai/gpt-5.2", Task: "Add login"},
+		{Kind: AllDone, Iteration: 2, Model: "openai/gpt-5.2", Task: "Add login"},
+		{Kind: Finished, Iteration: 2, Model: "openai/gpt-5.2", Task: "Add login", Detail: "complete"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		want[i].Project, want[i].Time, want[i].Started = "/work/app", got[i].Time, started
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWatcherTasks(t *testing.T) {
+	prd := func(s string) tasks.List { return tasks.Parse(strings.NewReader(s)) }
+	w := NewWatcher("/work/app", prd("- [ ] Set up\n- [ ] Add login\n"))
+
+	got := w.Tasks(prd("- [x] Set up\n- [ ] Add login\n"))
+	if len(got) != 1 || got[0].Kind != TaskDone || got[0].Task != "Set up" {
+		t.Errorf("checking one off = %+v", got)
+	}
+	got = w.Tasks(prd("- [x] Set up\n- [x] Add login\n"))
+	if len(got) != 2 || got[0].Task != "Add login" || got[1].Kind != AllDone {
+		t.Errorf("checking the last off = %+v", got)
+	}
+	if got := w.Tasks(prd("- [x] Set up\n- [x] Add login\n")); len(got) != 0 {
+		t.Errorf("nothing changed, got %+v", got)
+	}
+	if ev, ok := events.Parse("✅ Agent signaled completion."); len(w.Line("", ev, ok)) != 0 {
+		t.Error("all done was already reported")
+	}
+	// A new task starts it over.
+	w.Tasks(prd("- [x] Set up\n- [x] Add login\n- [ ] Add logout\n"))
+	if got := w.Tasks(prd("- [x] Set up\n- [x] Add login\n- [x] Add logout\n")); len(got) != 2 || got[1].Kind != AllDone {
+		t.Errorf("finishing the new task = %+v", got)
+	}
+}
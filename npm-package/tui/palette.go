@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"vibepup-tui/ui"
+)
+
+var (
+	paletteRunKey   = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "run"))
+	paletteCloseKey = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close"))
+	paletteUpKey    = key.NewBinding(key.WithKeys("up", "ctrl+p", "ctrl+k"), key.WithHelp("↑", "up"))
+	paletteDownKey  = key.NewBinding(key.WithKeys("down", "ctrl+n", "ctrl+j"), key.WithHelp("↓", "down"))
+)
+
+// paletteHeight is how many rows the palette's list may take.
+const paletteHeight = 12
+
+// openPalette lists the actions available on the current screen.
+func (m *model) openPalette() tea.Cmd {
+	m.paletteActions = m.paletteActions[:0]
+	var items []ui.PaletteItem
+	for _, a := range actions {
+		if a.ID == "palette" || !m.available(a) {
+			continue
+		}
+		item := ui.PaletteItem{Title: a.Title}
+		if a.Key != nil {
+			item.Key = a.Key(m.keys()).Help().Key
+		}
+		items = append(items, item)
+		m.paletteActions = append(m.paletteActions, a)
+	}
+	m.palette = ui.NewCommandPalette(m.theme, items)
+	m.paletteOpen = true
+	return m.palette.Input.Focus()
+}
+
+// updatePalette handles keys while the palette is open.
+func (m model) updatePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, paletteCloseKey):
+		m.paletteOpen = false
+		return m, nil
+	case key.Matches(msg, paletteUpKey):
+		m.palette.Move(-1)
+		return m, nil
+	case key.Matches(msg, paletteDownKey):
+		m.palette.Move(1)
+		return m, nil
+	case key.Matches(msg, paletteRunKey):
+		m.paletteOpen = false
+		i, ok := m.palette.Selected()
+		if !ok {
+			return m, nil
+		}
+		cmd := m.paletteActions[i].Run(&m)
+		return m, cmd
+	}
+	var cmd tea.Cmd
+	m.palette.Input, cmd = m.palette.Input.Update(msg)
+	m.palette.Filter()
+	return m, cmd
+}
+
+// paletteView draws the palette centred on the screen.
+func (m model) paletteView() string {
+	width := min(max(m.width/2, 40), m.width-4)
+	body := lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Foreground(m.theme.Accent).Bold(true).Render("COMMANDS"),
+		m.palette.View(width, paletteHeight),
+		"",
+		m.help.ShortHelpView([]key.Binding{paletteRunKey, paletteUpKey, paletteDownKey, paletteCloseKey}),
+	)
+	box := ui.BoxStyle.BorderForeground(m.theme.Accent).Render(body)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}
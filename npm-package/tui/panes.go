@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"vibepup-tui/activity"
+	"vibepup-tui/config"
+	"vibepup-tui/motion"
+	"vibepup-tui/persona"
+	"vibepup-tui/tasks"
+	"vibepup-tui/ui"
+)
+
+// arrangementFor builds the pane arrangement from the config: a preset,
+// optionally with its pane lists and split sizes overridden.
+func arrangementFor(cfg config.Config) ui.Arrangement {
+	a, ok := ui.Preset(cfg.Layout.Preset)
+	if !ok {
+		a = ui.Presets()[0]
+	}
+	if side, err := ui.ParsePanes(cfg.Layout.Side); err == nil && cfg.Layout.Side != "" {
+		a.Side = side
+	}
+	if bottom, err := ui.ParsePanes(cfg.Layout.Bottom); err == nil && cfg.Layout.Bottom != "" {
+		a.Bottom = bottom
+	}
+	if cfg.Layout.SideWidth > 0 {
+		a.SideWidth = cfg.Layout.SideWidth
+	}
+	if cfg.Layout.BottomHeight > 0 {
+		a.BottomHeight = cfg.Layout.BottomHeight
+	}
+	if len(a.Side) > 0 && a.SideWidth == 0 {
+		a.SideWidth = 30
+	}
+	if len(a.Bottom) > 0 && a.BottomHeight == 0 {
+		a.BottomHeight = 8
+	}
+	return a
+}
+
+// panes returns the arrangement as currently shown, minus toggled-off panes.
+func (m model) panes() ui.Arrangement {
+	a := m.arrangement
+	if !m.showActivity {
+		a = a.Without(ui.PaneActivity)
+	}
+	return a
+}
+
+// framed reports whether panes get borders; a lone log doesn't need one.
+func (m model) framed() bool {
+	return len(m.panes().Visible()) > 1
+}
+
+// footer renders what goes under the panes: the search bar and help.
+func (m model) footer() string {
+	var lines []string
+	if bar := m.searchBar(); bar != "" {
+		lines = append(lines, bar)
+	}
+	if m.viewport.LinkMode() {
+		lines = append(lines, m.help.ShortHelpView(m.keys().LinkHelp()))
+	} else {
+		lines = append(lines, m.help.View(m.helpKeys()))
+	}
+	lines = append(lines, m.statusBar())
+	// help can overrun its width by an item when the ellipsis doesn't fit.
+	lines = strings.Split(strings.Join(lines, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = ui.ClampWidth(line, m.width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// measure lays out the running screen around the rendered header and footer.
+func (m model) measure() ui.Layout {
+	header := ""
+	if m.panes().Header {
+		header = m.header()
+	}
+	return ui.Measure(m.width, m.height, header, m.footer())
+}
+
+// logSize returns the size of the log viewport inside its pane.
+func (m model) logSize() (int, int) {
+	r := m.measure().Split(m.panes())[ui.PaneLog]
+	w, h := r.Width, r.Height
+	if m.framed() {
+		w, h = w-2, h-2
+	}
+	return max(w, 1), max(h-m.problemsHeight(), 0)
+}
+
+// cycleFocus moves focus to the next (or previous) visible pane.
+func (m *model) cycleFocus(delta int) {
+	visible := m.panes().Visible()
+	i := 0
+	for j, p := range visible {
+		if p == m.focus {
+			i = j
+		}
+	}
+	m.focus = visible[((i+delta)%len(visible)+len(visible))%len(visible)]
+}
+
+// resizeSplit grows the split next to the focused pane: the bottom row when
+// a bottom pane has focus, otherwise the side column if there is one.
+func (m *model) resizeSplit(delta int) tea.Cmd {
+	a := &m.arrangement
+	bottom := false
+	for _, p := range a.Bottom {
+		bottom = bottom || p == m.focus
+	}
+	switch {
+	case bottom || (len(a.Side) == 0 && len(a.Bottom) > 0):
+		a.ResizeBottom(delta)
+	case len(a.Side) > 0:
+		a.ResizeSide(delta * 2)
+	default:
+		return nil
+	}
+	m.resize()
+	return m.saveLayoutLater()
+}
+
+// switchPreset moves to the next named arrangement.
+func (m *model) switchPreset() tea.Cmd {
+	m.arrangement = ui.NextPreset(m.arrangement.Name)
+	if !m.panes().Has(m.focus) {
+		m.focus = ui.PaneLog
+	}
+	m.resize()
+	return m.saveLayoutLater()
+}
+
+// layoutSaveDelay is how long the layout has to stay put before it's saved,
+// so a run of resizes writes the config once.
+const layoutSaveDelay = 2 * time.Second
+
+// layoutSaveMsg saves the layout, unless it changed again since.
+type layoutSaveMsg struct {
+	seq int
+}
+
+// layoutKeys are the settings saveLayout writes.
+var layoutKeys = []string{"layout.preset", "layout.side_width", "layout.bottom_height"}
+
+// saveLayoutLater saves the arrangement once it stops changing. Only a
+// layout that comes from the user config, or from nowhere, is saved: one
+// set by the project, the environment or a flag would win again next time.
+func (m *model) saveLayoutLater() tea.Cmd {
+	user := config.FileSource("user", config.UserPath())
+	for _, k := range layoutKeys {
+		if src := m.cfg.Source(k); src != config.SourceDefault && src != user {
+			return nil
+		}
+	}
+	m.layoutDirty = true
+	m.layoutSeq++
+	seq := m.layoutSeq
+	return tea.Tick(layoutSaveDelay, func(time.Time) tea.Msg { return layoutSaveMsg{seq} })
+}
+
+// saveLayout writes a changed arrangement to the user config so the next
+// session starts the same way.
+func (m *model) saveLayout() error {
+	if !m.layoutDirty {
+		return nil
+	}
+	m.layoutDirty = false
+	values := map[string]any{
+		layoutKeys[0]: m.arrangement.Name,
+		layoutKeys[1]: m.arrangement.SideWidth,
+		layoutKeys[2]: m.arrangement.BottomHeight,
+	}
+	src := config.FileSource("user", config.UserPath())
+	for k, v := range values {
+		_ = m.cfg.Set(k, v, src)
+	}
+	return config.UpdateFile(config.UserPath(), values)
+}
+
+// updatePaneKeys scrolls a focused side pane. It reports whether the key
+// was used.
+func (m *model) updatePaneKeys(msg tea.KeyMsg) bool {
+	if m.focus != ui.PaneTasks && m.focus != ui.PaneActivity {
+		return false
+	}
+	switch {
+	case key.Matches(msg, m.viewport.KeyMap.Up):
+		m.paneScroll[m.focus]--
+	case key.Matches(msg, m.viewport.KeyMap.Down):
+		m.paneScroll[m.focus]++
+	default:
+		return false
+	}
+	if m.focus == ui.PaneActivity {
+		m.paneScroll[m.focus] = max(m.paneScroll[m.focus], 0)
+	} else {
+		// Tasks scroll relative to the next task; stop at the top.
+		m.paneScroll[m.focus] = max(m.paneScroll[m.focus], -ui.TasksPanel{}.NextOffset(m.tasks))
+	}
+	return true
+}
+
+// reloadTasks re-reads the PRD checklist.
+func (m *model) reloadTasks() {
+	if list, err := tasks.Load(m.projectDir); err == nil {
+		m.tasks = list
+		m.alerts.tasks(list)
+	}
+}
+
+// runningView composes the header, panes and footer.
+func (m model) runningView() string {
+	a := m.panes()
+	layout := m.measure()
+	body := layout.Compose(a, func(p ui.Pane, r ui.Rect) string {
+		inner := r
+		if m.framed() {
+			inner = ui.Rect{Width: r.Width - 2, Height: r.Height - 2}
+		}
+		content := m.renderPane(p, inner)
+		if !m.framed() {
+			return ui.Fit(content, r.Width, r.Height)
+		}
+		return ui.Frame(string(p), content, r, p == m.focus, m.theme.Border, m.theme.Accent)
+	})
+	sections := []string{}
+	if a.Header {
+		sections = append(sections, m.header())
+	}
+	sections = append(sections, body, m.footer())
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// renderPane draws a pane's content at the given inner size.
+func (m model) renderPane(p ui.Pane, r ui.Rect) string {
+	switch p {
+	case ui.PaneLog:
+		out := m.viewport.View()
+		if h := m.problemsHeight(); h > 0 {
+			out += "\n" + ui.ProblemsPanel{Theme: m.theme}.Render(m.problems, r.Width, h)
+		}
+		return out
+	case ui.PaneTasks:
+		panel := ui.TasksPanel{Theme: m.theme, ASCII: m.asciiOnly()}
+		panel.Offset = panel.NextOffset(m.tasks) + m.paneScroll[p]
+		return panel.Render(m.tasks, r.Width, r.Height)
+	case ui.PaneActivity:
+		if m.activity.Len() == 0 {
+			return lipgloss.NewStyle().Foreground(m.theme.Muted).Render("waiting for the first tool call")
+		}
+		panel := ui.ActivityPanel{Theme: m.theme, Offset: m.paneScroll[p]}
+		return panel.Render(m.activity, r.Width, r.Height, time.Now())
+	case ui.PaneDog:
+		return m.dogView()
+	case ui.PaneStatus:
+		return m.statusView()
+	}
+	return ""
+}
+
+// dogView is the dog and its quip, shown in the header or the dog pane.
+func (m model) dogView() string {
+	dog := motion.GetDogFrame(m.dogState, m.frame)
+	if m.asciiOnly() {
+		dog = motion.GetASCIIDogFrame(m.dogState, m.frame)
+	}
+	if m.cfg.Quiet {
+		return dog
+	}
+	return dog + "\n" + m.quip()
+}
+
+// statusView summarises the run in the status pane.
+func (m model) statusView() string {
+	label := lipgloss.NewStyle().Foreground(m.theme.Muted)
+	value := lipgloss.NewStyle().Foreground(m.theme.Foreground)
+	row := func(k, v string) string { return label.Render(fmt.Sprintf("%-8s", k)) + value.Render(v) }
+
+	runner := "idle"
+	if m.runner != nil {
+		runner = "running"
+	}
+	loop := "-"
+	if m.iteration > 0 {
+		loop = fmt.Sprintf("%d (%s)", m.iteration, m.phase)
+	}
+	elapsed := "-"
+	if !m.runStarted.IsZero() {
+		elapsed = ui.FormatDuration(time.Since(m.runStarted).Truncate(time.Second))
+	}
+	return strings.Join([]string{
+		row("runner", runner),
+		row("loop", loop),
+		row("mode", string(m.activity.Mode())),
+		row("tasks", fmt.Sprintf("%d/%d", m.tasks.Done(), m.tasks.Total())),
+		row("elapsed", elapsed),
+		row("layout", m.arrangement.Name),
+	}, "\n")
+}
+
+// quip picks what the persona says, reacting to a thrashing agent.
+func (m model) quip() string {
+	if m.activity.Mode() == activity.Thrashing {
+		return persona.ThrashQuip(m.snark)
+	}
+	return persona.RandomQuip(m.snark)
+}
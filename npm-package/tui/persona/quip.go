@@ -13,6 +13,11 @@ const (
 	Unhinged SnarkLevel = "unhinged"
 )
 
+// Levels lists the snark levels in increasing order of spice.
+func Levels() []SnarkLevel {
+	return []SnarkLevel{Mild, Spicy, Unhinged}
+}
+
 func ParseSnark(s string) SnarkLevel {
 	switch strings.ToLower(s) {
 	case "mild":
@@ -87,3 +92,43 @@ func RandomQuip(level SnarkLevel) string {
 		return spicy[rand.Intn(len(spicy))]
 	}
 }
+
+// ActivityStatus returns a status for what the agent is doing, as reported
+// by the activity feed ("exploring", "editing", "running" or "thrashing").
+// It returns "" for anything else so callers can fall back to GetStatus.
+func ActivityStatus(mode string, level SnarkLevel) string {
+	statuses := map[string]map[SnarkLevel]string{
+		"exploring": {Mild: "READING THE CODE", Spicy: "SNIFFING AROUND", Unhinged: "DIGGING THROUGH YOUR TRASH"},
+		"editing":   {Mild: "EDITING FILES", Spicy: "CHEWING ON CODE", Unhinged: "REWRITING HISTORY"},
+		"running":   {Mild: "RUNNING COMMANDS", Spicy: "POKING THE SHELL", Unhinged: "PRESSING ALL THE BUTTONS"},
+		"thrashing": {Mild: "RETRYING", Spicy: "CHASING ITS TAIL", Unhinged: "SPIRALLING"},
+	}
+	return statuses[mode][level]
+}
+
+// ThrashQuip is said when the agent keeps repeating the same tool call.
+func ThrashQuip(level SnarkLevel) string {
+	mild := []string{
+		"Same thing again? Maybe try another way.",
+		"Going in circles a little.",
+	}
+
+	spicy := []string{
+		"Definition of insanity, anyone?",
+		"Third time's the charm. Or the fourth.",
+	}
+
+	unhinged := []string{
+		"THE LOOP. THE LOOP. THE LOOP.",
+		"I have seen this call before. I will see it again.",
+	}
+
+	switch level {
+	case Mild:
+		return mild[rand.Intn(len(mild))]
+	case Unhinged:
+		return unhinged[rand.Intn(len(unhinged))]
+	default:
+		return spicy[rand.Intn(len(spicy))]
+	}
+}
@@ -0,0 +1,196 @@
+package problems
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Problem is a single compiler, linter or test failure found in the output.
+type Problem struct {
+	Source  string // go, go-test, tsc, eslint, python, jest
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// Location formats the problem position as file:line[:col].
+func (p Problem) Location() string {
+	if p.File == "" {
+		return ""
+	}
+	loc := p.File
+	if p.Line > 0 {
+		loc += ":" + strconv.Itoa(p.Line)
+		if p.Column > 0 {
+			loc += ":" + strconv.Itoa(p.Column)
+		}
+	}
+	return loc
+}
+
+var (
+	goLine        = regexp.MustCompile(`^\s*(\S+\.go):(\d+)(?::(\d+))?:\s+(.+)$`)
+	goTestFail    = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+	tscParen      = regexp.MustCompile(`^(\S+\.[cm]?tsx?)\((\d+),(\d+)\):\s+error\s+(TS\d+):\s+(.+)$`)
+	tscPretty     = regexp.MustCompile(`^(\S+\.[cm]?tsx?):(\d+):(\d+)\s+-\s+error\s+(TS\d+):\s+(.+)$`)
+	eslintFile    = regexp.MustCompile(`^(\S+\.(?:[cm]?[jt]sx?|vue|svelte))$`)
+	eslintRow     = regexp.MustCompile(`^\s+(\d+):(\d+)\s+error\s+(.+?)(?:\s{2,}(\S+))?$`)
+	eslintCompact = regexp.MustCompile(`^(\S+): line (\d+), col (\d+), Error - (.+)$`)
+	pyTraceback   = regexp.MustCompile(`^Traceback \(most recent call last\):`)
+	pyFrame       = regexp.MustCompile(`^\s+File "(.+)", line (\d+)`)
+	pyException   = regexp.MustCompile(`^([A-Za-z_][\w.]*(?:Error|Exception|Exit|Interrupt)):?\s*(.*)$`)
+	pyShort       = regexp.MustCompile(`^(\S+\.py):(\d+):\s+(\w+(?:Error|Exception).*)$`)
+	jestTitle     = regexp.MustCompile(`^\s*● (.+)$`)
+	jestFrame     = regexp.MustCompile(`^\s+at (?:.*\()?([^()\s]+):(\d+):(\d+)\)?$`)
+	jestSummary   = regexp.MustCompile(`^(Tests|Test Suites):\s`)
+)
+
+// Extractor turns raw output lines into problems. Some formats (Python
+// tracebacks, ESLint stylish output, Jest failures) span several lines, so
+// the extractor keeps a little state between calls to Feed.
+type Extractor struct {
+	eslintFile string
+	pyFrame    *Problem
+	inPy       bool
+	jestTitle  string
+}
+
+// Feed parses one output line and returns any problems it completes.
+func (e *Extractor) Feed(line string) []Problem {
+	line = strings.TrimRight(ansi.Strip(line), "\r")
+	line = strings.TrimPrefix(line, "ERR: ")
+
+	if out, ok := e.feedPython(line); ok {
+		return out
+	}
+	if out, ok := e.feedJest(line); ok {
+		return out
+	}
+
+	if m := tscParen.FindStringSubmatch(line); m != nil {
+		return []Problem{tsc(m)}
+	}
+	if m := tscPretty.FindStringSubmatch(line); m != nil {
+		return []Problem{tsc(m)}
+	}
+	if m := goLine.FindStringSubmatch(line); m != nil {
+		src := "go"
+		if strings.HasSuffix(m[1], "_test.go") {
+			src = "go-test"
+		}
+		return []Problem{{Source: src, File: cleanPath(m[1]), Line: atoi(m[2]), Column: atoi(m[3]), Message: m[4]}}
+	}
+	if m := goTestFail.FindStringSubmatch(line); m != nil {
+		return []Problem{{Source: "go-test", Message: m[1] + " failed"}}
+	}
+	if m := pyShort.FindStringSubmatch(line); m != nil {
+		return []Problem{{Source: "python", File: cleanPath(m[1]), Line: atoi(m[2]), Message: m[3]}}
+	}
+	if m := eslintCompact.FindStringSubmatch(line); m != nil {
+		return []Problem{{Source: "eslint", File: cleanPath(m[1]), Line: atoi(m[2]), Column: atoi(m[3]), Message: m[4]}}
+	}
+	return e.feedESLint(line)
+}
+
+func (e *Extractor) feedESLint(line string) []Problem {
+	if strings.TrimSpace(line) == "" {
+		e.eslintFile = ""
+		return nil
+	}
+	if m := eslintFile.FindStringSubmatch(line); m != nil {
+		e.eslintFile = cleanPath(m[1])
+		return nil
+	}
+	if e.eslintFile == "" {
+		return nil
+	}
+	m := eslintRow.FindStringSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	msg := m[3]
+	if m[4] != "" {
+		msg += " (" + m[4] + ")"
+	}
+	return []Problem{{Source: "eslint", File: e.eslintFile, Line: atoi(m[1]), Column: atoi(m[2]), Message: msg}}
+}
+
+func (e *Extractor) feedPython(line string) ([]Problem, bool) {
+	if pyTraceback.MatchString(line) {
+		e.inPy = true
+		e.pyFrame = nil
+		return nil, true
+	}
+	if !e.inPy {
+		return nil, false
+	}
+	if m := pyFrame.FindStringSubmatch(line); m != nil {
+		e.pyFrame = &Problem{Source: "python", File: cleanPath(m[1]), Line: atoi(m[2])}
+		return nil, true
+	}
+	if strings.HasPrefix(line, " ") || strings.TrimSpace(line) == "" {
+		// Source excerpts and caret markers between frames.
+		return nil, true
+	}
+	e.inPy = false
+	m := pyException.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	p := Problem{Source: "python", Message: strings.TrimSpace(m[1] + ": " + m[2])}
+	if m[2] == "" {
+		p.Message = m[1]
+	}
+	if e.pyFrame != nil {
+		p.File, p.Line = e.pyFrame.File, e.pyFrame.Line
+	}
+	e.pyFrame = nil
+	return []Problem{p}, true
+}
+
+func (e *Extractor) feedJest(line string) ([]Problem, bool) {
+	if m := jestTitle.FindStringSubmatch(line); m != nil {
+		out := e.flushJest()
+		e.jestTitle = strings.TrimSpace(m[1])
+		return out, true
+	}
+	if e.jestTitle == "" {
+		return nil, false
+	}
+	if jestSummary.MatchString(line) {
+		return e.flushJest(), true
+	}
+	m := jestFrame.FindStringSubmatch(line)
+	if m == nil || strings.Contains(m[1], "node_modules") || strings.HasPrefix(m[1], "node:") {
+		return nil, true
+	}
+	p := Problem{Source: "jest", File: cleanPath(m[1]), Line: atoi(m[2]), Column: atoi(m[3]), Message: e.jestTitle}
+	e.jestTitle = ""
+	return []Problem{p}, true
+}
+
+func (e *Extractor) flushJest() []Problem {
+	if e.jestTitle == "" {
+		return nil
+	}
+	p := Problem{Source: "jest", Message: e.jestTitle}
+	e.jestTitle = ""
+	return []Problem{p}
+}
+
+func tsc(m []string) Problem {
+	return Problem{Source: "tsc", File: cleanPath(m[1]), Line: atoi(m[2]), Column: atoi(m[3]), Message: m[4] + ": " + m[5]}
+}
+
+func cleanPath(p string) string {
+	return strings.TrimPrefix(p, "./")
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,110 @@
+package problems
+
+import "testing"
+
+func feedAll(lines ...string) []Problem {
+	var e Extractor
+	var out []Problem
+	for _, l := range lines {
+		out = append(out, e.Feed(l)...)
+	}
+	return out
+}
+
+func TestExtractFormats(t *testing.T) {
+	cases := []struct {
+		name  string
+		lines []string
+		want  Problem
+	}{
+		{
+			name:  "go compiler",
+			lines: []string{"./ui/viewport.go:12:5: undefined: foo"},
+			want:  Problem{Source: "go", File: "ui/viewport.go", Line: 12, Column: 5, Message: "undefined: foo"},
+		},
+		{
+			name:  "go test",
+			lines: []string{"    main_test.go:25: expected 3, got 4"},
+			want:  Problem{Source: "go-test", File: "main_test.go", Line: 25, Message: "expected 3, got 4"},
+		},
+		{
+			name:  "tsc",
+			lines: []string{"src/app.ts(42,7): error TS2322: Type 'string' is not assignable to type 'number'."},
+			want:  Problem{Source: "tsc", File: "src/app.ts", Line: 42, Column: 7, Message: "TS2322: Type 'string' is not assignable to type 'number'."},
+		},
+		{
+			name:  "tsc pretty",
+			lines: []string{"src/app.ts:42:7 - error TS2304: Cannot find name 'foo'."},
+			want:  Problem{Source: "tsc", File: "src/app.ts", Line: 42, Column: 7, Message: "TS2304: Cannot find name 'foo'."},
+		},
+		{
+			name: "eslint stylish",
+			lines: []string{
+				"/home/pup/app/src/index.js",
+				"  3:10  error  'x' is defined but never used  no-unused-vars",
+			},
+			want: Problem{Source: "eslint", File: "/home/pup/app/src/index.js", Line: 3, Column: 10, Message: "'x' is defined but never used (no-unused-vars)"},
+		},
+		{
+			name: "python traceback",
+			lines: []string{
+				"Traceback (most recent call last):",
+				`  File "/usr/lib/python3/runpy.py", line 88, in _run_code`,
+				`  File "app.py", line 7, in <module>`,
+				"    main()",
+				"ValueError: bad vibes",
+			},
+			want: Problem{Source: "python", File: "app.py", Line: 7, Message: "ValueError: bad vibes"},
+		},
+		{
+			name: "jest",
+			lines: []string{
+				"  ● sum › adds numbers",
+				"",
+				"    expect(received).toBe(expected)",
+				"      at Object.<anonymous> (src/sum.test.js:4:13)",
+			},
+			want: Problem{Source: "jest", File: "src/sum.test.js", Line: 4, Column: 13, Message: "sum › adds numbers"},
+		},
+		{
+			name:  "ansi coloured",
+			lines: []string{"\x1b[91msrc/app.ts(1,1): error TS1005: ';' expected.\x1b[0m"},
+			want:  Problem{Source: "tsc", File: "src/app.ts", Line: 1, Column: 1, Message: "TS1005: ';' expected."},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := feedAll(tc.lines...)
+			if len(got) != 1 {
+				t.Fatalf("expected 1 problem, got %d: %+v", len(got), got)
+			}
+			if got[0] != tc.want {
+				t.Fatalf("got %+v, want %+v", got[0], tc.want)
+			}
+		})
+	}
+}
+
+func TestSetTracksRecurringProblems(t *testing.T) {
+	s := NewSet()
+	line := "main.go:3:1: syntax error"
+
+	s.BeginIteration(1)
+	s.Feed(line)
+	s.Feed(line)
+	s.Feed("other.go:1:1: unused import")
+	s.BeginIteration(2)
+	s.Feed(line)
+
+	if s.Len() != 2 {
+		t.Fatalf("expected 2 distinct problems, got %d", s.Len())
+	}
+	if s.Open() != 1 {
+		t.Fatalf("expected 1 open problem, got %d", s.Open())
+	}
+	top := s.List()[0]
+	if top.File != "main.go" || top.Count != 3 || top.Iterations != 2 {
+		t.Fatalf("unexpected top entry: %+v", top)
+	}
+}
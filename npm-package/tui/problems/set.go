@@ -0,0 +1,103 @@
+package problems
+
+import "sort"
+
+// Entry is a deduplicated problem plus how often the agent has hit it.
+type Entry struct {
+	Problem
+	Count      int // total occurrences
+	Iterations int // distinct iterations it appeared in
+	First      int // iteration it was first seen in
+	Last       int // iteration it was last seen in
+}
+
+// Open reports whether the problem showed up in the given iteration.
+func (e Entry) Open(iteration int) bool {
+	return e.Last == iteration
+}
+
+// Set collects problems across iterations, deduplicating by location and
+// message so recurring failures stand out.
+type Set struct {
+	extractor Extractor
+	entries   map[string]*Entry
+	iteration int
+}
+
+func NewSet() *Set {
+	return &Set{entries: map[string]*Entry{}}
+}
+
+// BeginIteration marks the start of a new runner loop.
+func (s *Set) BeginIteration(n int) {
+	s.iteration = n
+	s.extractor = Extractor{}
+}
+
+// Iteration returns the loop the set is currently attributing problems to.
+func (s *Set) Iteration() int {
+	return s.iteration
+}
+
+// Feed runs a line through the extractor and returns true if it produced
+// any problems.
+func (s *Set) Feed(line string) bool {
+	found := s.extractor.Feed(line)
+	for _, p := range found {
+		s.add(p)
+	}
+	return len(found) > 0
+}
+
+func (s *Set) add(p Problem) {
+	k := p.Source + "\x00" + p.Location() + "\x00" + p.Message
+	e, ok := s.entries[k]
+	if !ok {
+		e = &Entry{Problem: p, First: s.iteration, Last: s.iteration, Iterations: 1}
+		s.entries[k] = e
+	}
+	if e.Last != s.iteration {
+		e.Last = s.iteration
+		e.Iterations++
+	}
+	e.Count++
+}
+
+// Len returns the number of distinct problems.
+func (s *Set) Len() int {
+	return len(s.entries)
+}
+
+// Open returns the number of problems seen in the current iteration.
+func (s *Set) Open() int {
+	n := 0
+	for _, e := range s.entries {
+		if e.Open(s.iteration) {
+			n++
+		}
+	}
+	return n
+}
+
+// List returns problems with the current iteration's first, most
+// persistent first within that.
+func (s *Set) List() []Entry {
+	out := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Open(s.iteration) != b.Open(s.iteration) {
+			return a.Open(s.iteration)
+		}
+		if a.Iterations != b.Iterations {
+			return a.Iterations > b.Iterations
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return out
+}
@@ -0,0 +1,46 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// EditorDoneMsg is sent when the user's editor exits and the TUI resumes
+type EditorDoneMsg struct {
+	Path string
+	Err  error
+}
+
+// EditorCmd builds the command that opens path at line in $VISUAL/$EDITOR.
+// Most terminal editors take "+line file"; a few GUI editors need their own
+// goto syntax.
+func EditorCmd(path string, line, col int) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	parts := strings.Fields(editor)
+	name, args := parts[0], parts[1:]
+	if col < 1 {
+		col = 1
+	}
+
+	switch filepath.Base(name) {
+	case "code", "code-insiders", "codium", "cursor", "windsurf":
+		args = append(args, "--goto", fmt.Sprintf("%s:%d:%d", path, line, col))
+	case "subl", "zed", "hx", "helix":
+		args = append(args, fmt.Sprintf("%s:%d:%d", path, line, col))
+	default:
+		if line > 0 {
+			args = append(args, fmt.Sprintf("+%d", line))
+		}
+		args = append(args, path)
+	}
+	return exec.Command(name, args...)
+}
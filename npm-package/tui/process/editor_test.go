@@ -0,0 +1,31 @@
+package process
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEditorCmd(t *testing.T) {
+	tests := []struct {
+		visual, editor string
+		line, col      int
+		want           []string
+	}{
+		{"", "", 12, 3, []string{"vi", "+12", "/p/a.go"}},
+		{"", "nvim", 12, 3, []string{"nvim", "+12", "/p/a.go"}},
+		{"", "emacsclient -t", 0, 0, []string{"emacsclient", "-t", "/p/a.go"}},
+		{"code --wait", "vim", 12, 3, []string{"code", "--wait", "--goto", "/p/a.go:12:3"}},
+		{"/usr/bin/cursor", "", 5, 0, []string{"/usr/bin/cursor", "--goto", "/p/a.go:5:1"}},
+		{"subl -w", "", 12, 3, []string{"subl", "-w", "/p/a.go:12:3"}},
+		{"zed", "", 12, 3, []string{"zed", "/p/a.go:12:3"}},
+		{"", "hx", 12, 0, []string{"hx", "/p/a.go:12:1"}},
+		{"helix", "", 7, 2, []string{"helix", "/p/a.go:7:2"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("VISUAL", tt.visual)
+		t.Setenv("EDITOR", tt.editor)
+		if got := EditorCmd("/p/a.go", tt.line, tt.col).Args; !slices.Equal(got, tt.want) {
+			t.Errorf("VISUAL=%q EDITOR=%q: args = %q, want %q", tt.visual, tt.editor, got, tt.want)
+		}
+	}
+}
@@ -3,8 +3,14 @@ package process
 import (
 	"bufio"
 	"context"
+	"errors"
+	"io"
+	"os"
 	"os/exec"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -17,17 +23,56 @@ type DoneMsg struct {
 	Err error
 }
 
+// ExitError reports a non-zero exit the TUI didn't see first-hand, such as
+// that of a runner owned by a supervisor.
+type ExitError struct {
+	Code int
+	Msg  string
+}
+
+func (e *ExitError) Error() string { return e.Msg }
+
+// ExitCode returns the runner's exit code.
+func (e *ExitError) ExitCode() int { return e.Code }
+
+// ErrExitUnknown is the exit of an adopted runner: it isn't our child, so
+// all we see is its output ending.
+var ErrExitUnknown = errors.New("runner exited; its status is unknown")
+
+// Control drives a runner owned by another process. Commands are "pause",
+// "resume", "stop" and "kill".
+type Control interface {
+	Send(cmd string) error
+}
+
 // Runner handles the execution of the external process
 type Runner struct {
 	Cmd        *exec.Cmd
 	Cancel     context.CancelFunc
 	OutputChan chan string
+	paused     bool
+	pid        int
+
+	// The read ends of the output pipes, handed over by Detach.
+	stdout, stderr *os.File
+	exited         chan struct{}
+
+	// polled is set once the output is read through WaitForOutput, which
+	// closes drained on reading its end.
+	polled    atomic.Bool
+	drained   chan struct{}
+	drainOnce sync.Once
+
+	// ctl is set for a runner owned by a supervisor.
+	ctl Control
 }
 
-// Start launches the command in a new process group to allow deep killing
-func Start(ctx context.Context, name string, args []string) (*Runner, tea.Cmd) {
+// Start launches the command in a new process group to allow deep killing.
+// env is appended to the TUI's own environment.
+func Start(ctx context.Context, name string, args []string, env []string) (*Runner, tea.Cmd) {
 	ctx, cancel := context.WithCancel(ctx)
 	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
 
 	// Create a new process group so we can kill the whole tree later
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -40,55 +85,220 @@ func Start(ctx context.Context, name string, args []string) (*Runner, tea.Cmd) {
 		Cmd:        cmd,
 		Cancel:     cancel,
 		OutputChan: make(chan string),
+		exited:     make(chan struct{}),
+		drained:    make(chan struct{}),
 	}
+	runner.stdout, _ = stdout.(*os.File)
+	runner.stderr, _ = stderr.(*os.File)
 
 	if err := cmd.Start(); err != nil {
 		cancel()
 		return nil, func() tea.Msg { return DoneMsg{Err: err} }
 	}
+	runner.pid = cmd.Process.Pid
+	streamed := runner.stream(stdout, stderr)
 
-	// Stream output to channel
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			runner.OutputChan <- scanner.Text()
-		}
-	}()
-
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			runner.OutputChan <- "ERR: " + scanner.Text()
-		}
-	}()
-
-	// Wait for completion in background
+	// Wait for completion in background. Wait closes the pipes, so it has
+	// to wait for the output to be read to the end first.
 	cmdCmd := func() tea.Msg {
+		<-streamed
 		err := cmd.Wait()
-		return DoneMsg{Err: err}
+		close(runner.exited)
+		return runner.Done(err)
 	}
 
 	return runner, cmdCmd
 }
 
+// Adopt takes over a runner started by another TUI that detached from it,
+// reading its output from the pipes that TUI handed over. The returned
+// command reports ErrExitUnknown once the output ends.
+func Adopt(pid int, stdout, stderr *os.File) (*Runner, tea.Cmd) {
+	runner := &Runner{
+		OutputChan: make(chan string),
+		pid:        pid,
+		exited:     make(chan struct{}),
+		drained:    make(chan struct{}),
+	}
+	streamed := runner.stream(stdout, stderr)
+	return runner, func() tea.Msg {
+		<-streamed
+		close(runner.exited)
+		return runner.Done(ErrExitUnknown)
+	}
+}
+
+// Remote returns a stand-in for a runner owned by a supervisor: whoever
+// holds the connection feeds OutputChan, closing it when the output ends,
+// and ctl carries the commands.
+func Remote(pid int, paused bool, ctl Control) *Runner {
+	return &Runner{OutputChan: make(chan string), pid: pid, paused: paused, ctl: ctl, drained: make(chan struct{})}
+}
+
+// Done reports the runner's exit. When the output is read through
+// WaitForOutput it first waits for the last line to be handled, so the
+// DoneMsg never overtakes an OutputMsg.
+func (r *Runner) Done(err error) tea.Msg {
+	if r.polled.Load() {
+		<-r.drained
+	}
+	return DoneMsg{Err: err}
+}
+
+// Remote reports whether the runner is owned by a supervisor.
+func (r *Runner) Remote() bool {
+	return r.ctl != nil
+}
+
+// stream sends both pipes' lines to OutputChan, closing it and the
+// returned channel when both end.
+func (r *Runner) stream(stdout, stderr io.Reader) <-chan struct{} {
+	var wg sync.WaitGroup
+	scan := func(pipe io.Reader, prefix string) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(pipe)
+		for scanner.Scan() {
+			r.OutputChan <- prefix + scanner.Text()
+		}
+	}
+	wg.Add(2)
+	go scan(stdout, "")
+	go scan(stderr, "ERR: ")
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(r.OutputChan)
+		close(done)
+	}()
+	return done
+}
+
 // Kill stops the process and its children
 func (r *Runner) Kill() {
-	if r.Cmd != nil && r.Cmd.Process != nil {
+	if r.ctl != nil {
+		_ = r.ctl.Send("kill")
+		return
+	}
+	if r.pid != 0 {
 		// Kill the entire process group (negative PID)
-		_ = syscall.Kill(-r.Cmd.Process.Pid, syscall.SIGKILL)
+		_ = syscall.Kill(-r.pid, syscall.SIGKILL)
 	}
 	if r.Cancel != nil {
 		r.Cancel()
 	}
 }
 
-// WaitForOutput returns a command that waits for the next line of output
+// WaitForOutput returns a command that waits for the next line of output.
+// Ask for the next line only once this one is handled; the DoneMsg waits
+// for the end of the output to be read that way.
 func (r *Runner) WaitForOutput() tea.Cmd {
+	r.polled.Store(true)
 	return func() tea.Msg {
 		line, ok := <-r.OutputChan
 		if !ok {
+			r.drainOnce.Do(func() { close(r.drained) })
 			return nil
 		}
 		return OutputMsg(line)
 	}
 }
+
+// Pid returns the runner's process ID, or 0 if it didn't start.
+func (r *Runner) Pid() int {
+	return r.pid
+}
+
+// Pause stops the runner's whole process group with SIGSTOP.
+func (r *Runner) Pause() error {
+	if err := r.signal("pause", syscall.SIGSTOP); err != nil {
+		return err
+	}
+	r.paused = true
+	return nil
+}
+
+// Resume continues a paused process group.
+func (r *Runner) Resume() error {
+	if err := r.signal("resume", syscall.SIGCONT); err != nil {
+		return err
+	}
+	r.paused = false
+	return nil
+}
+
+// signal sends sig to the process group, or cmd to the supervisor.
+func (r *Runner) signal(cmd string, sig syscall.Signal) error {
+	if r.ctl != nil {
+		return r.ctl.Send(cmd)
+	}
+	if r.pid == 0 {
+		return errors.New("runner isn't running")
+	}
+	return syscall.Kill(-r.pid, sig)
+}
+
+// Paused reports whether the runner is stopped by Pause.
+func (r *Runner) Paused() bool {
+	return r.paused
+}
+
+// StopStep is how long a stop waits for the runner before escalating to
+// the next signal.
+const StopStep = 5 * time.Second
+
+// stopLadder is the escalation Stop walks: ask, then insist.
+var stopLadder = []syscall.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL}
+
+// Stop asks the process group to exit with SIGINT, escalating to SIGTERM
+// and then SIGKILL each time step passes without it doing so. It returns
+// at once; the DoneMsg reports the exit. A paused runner is resumed first
+// so it can handle the signals. A supervisor's runner is stopped by the
+// supervisor.
+func (r *Runner) Stop(step time.Duration) {
+	if r.ctl != nil {
+		_ = r.ctl.Send("stop")
+		return
+	}
+	pid := r.Pid()
+	if pid == 0 {
+		return
+	}
+	if r.paused {
+		_ = r.Resume()
+	}
+	go func() {
+		for _, sig := range stopLadder {
+			_ = syscall.Kill(-pid, sig)
+			select {
+			case <-r.exited:
+				return
+			case <-time.After(step):
+			}
+		}
+	}()
+}
+
+// Detach hands the runner's output over to drain, which is started with
+// the stdout and stderr pipes as file descriptors 3 and 4 in a session of
+// its own, so both outlive the TUI. Lines the TUI has already read but not
+// yet shown are lost. A supervisor's runner needs no handing over.
+func (r *Runner) Detach(drain *exec.Cmd) error {
+	if r.ctl != nil {
+		return nil
+	}
+	if r.stdout == nil || r.stderr == nil {
+		return errors.New("runner output isn't a pipe")
+	}
+	if r.paused {
+		if err := r.Resume(); err != nil {
+			return err
+		}
+	}
+	drain.ExtraFiles = []*os.File{r.stdout, r.stderr}
+	drain.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := drain.Start(); err != nil {
+		return err
+	}
+	return drain.Process.Release()
+}
@@ -0,0 +1,54 @@
+package process
+
+import (
+	"context"
+	"testing"
+)
+
+const fastLines = 3000
+
+func TestStartKeepsTheLastLines(t *testing.T) {
+	for range 20 {
+		r, done := Start(context.Background(), "sh", []string{"-c", "seq 1 3000; seq 1 5 >&2"}, nil)
+		exited := make(chan DoneMsg, 1)
+		go func() { exited <- done().(DoneMsg) }()
+		n := 0
+		for range r.OutputChan {
+			n++
+		}
+		if msg := <-exited; msg.Err != nil {
+			t.Fatal(msg.Err)
+		}
+		if n != fastLines+5 {
+			t.Fatalf("read %d lines, want %d", n, fastLines+5)
+		}
+	}
+}
+
+func TestDoneWaitsForTheOutputToBeRead(t *testing.T) {
+	r, done := Start(context.Background(), "sh", []string{"-c", "seq 1 3000"}, nil)
+	wait := r.WaitForOutput()
+	exited := make(chan struct{})
+	go func() {
+		done()
+		close(exited)
+	}()
+	n := 0
+	for {
+		msg := wait()
+		if msg == nil {
+			break
+		}
+		select {
+		case <-exited:
+			t.Fatalf("DoneMsg came after %d lines, before the last", n)
+		default:
+		}
+		n++
+		wait = r.WaitForOutput()
+	}
+	<-exited
+	if n != fastLines {
+		t.Errorf("read %d lines, want %d", n, fastLines)
+	}
+}
@@ -0,0 +1,155 @@
+package process
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ShellReadyMsg is sent once the project has been snapshotted on the way
+// into a subshell. Before is compared with the project on the way out.
+type ShellReadyMsg struct {
+	Before Snapshot
+}
+
+// SnapshotCmd snapshots dir in the background, as walking a big project
+// would hold up the UI.
+func SnapshotCmd(dir string) tea.Cmd {
+	return func() tea.Msg {
+		return ShellReadyMsg{Before: TakeSnapshot(dir)}
+	}
+}
+
+// ShellExitedMsg is sent when the user leaves a subshell, before the
+// project is compared with Before.
+type ShellExitedMsg struct {
+	Before Snapshot
+	Err    error
+}
+
+// DiffCmd snapshots dir in the background and reports what changed since
+// the subshell was opened.
+func DiffCmd(dir string, exited ShellExitedMsg) tea.Cmd {
+	return func() tea.Msg {
+		after := TakeSnapshot(dir)
+		return ShellDoneMsg{Changes: after.Diff(exited.Before), Partial: after.Partial || exited.Before.Partial, Err: exited.Err}
+	}
+}
+
+// ShellDoneMsg is sent once the project has been compared after a
+// subshell. Changes lists the files created, modified or removed in the
+// project while it was open; if Partial, only those among the files
+// snapshotted, and none removed.
+type ShellDoneMsg struct {
+	Changes []Change
+	Partial bool
+	Err     error
+}
+
+// ShellCmd spawns the user's $SHELL in dir, marking the environment so
+// prompts and scripts can tell they are inside a vibepup subshell.
+func ShellCmd(dir string) *exec.Cmd {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"VIBEPUP_SUBSHELL=1",
+		"VIBEPUP_PROJECT_DIR="+dir,
+	)
+	return cmd
+}
+
+// ChangeKind describes how a file differs between two snapshots.
+type ChangeKind string
+
+const (
+	Created  ChangeKind = "created"
+	Modified ChangeKind = "modified"
+	Removed  ChangeKind = "removed"
+)
+
+type Change struct {
+	Kind ChangeKind
+	Path string
+}
+
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// Snapshot records size and mtime for every file under a directory.
+type Snapshot struct {
+	files map[string]stamp
+	// Partial is a walk cut off at the limit, missing the files past it.
+	Partial bool
+}
+
+// maxSnapshotFiles bounds the walk so huge monorepos don't stall the TUI.
+const maxSnapshotFiles = 50000
+
+var skipDirs = map[string]bool{".git": true, "node_modules": true, ".ralph": true}
+
+// TakeSnapshot walks dir, skipping VCS metadata, dependencies and runner logs.
+func TakeSnapshot(dir string) Snapshot {
+	return takeSnapshot(dir, maxSnapshotFiles)
+}
+
+// takeSnapshot is TakeSnapshot stopping at limit files.
+func takeSnapshot(dir string, limit int) Snapshot {
+	snap := Snapshot{files: map[string]stamp{}}
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(snap.files) >= limit {
+			snap.Partial = true
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		snap.files[rel] = stamp{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return snap
+}
+
+// Diff lists what changed from before to s, sorted by path. A file missing
+// from a partial snapshot may just be past the limit, so if either is
+// partial no file is listed as removed.
+func (s Snapshot) Diff(before Snapshot) []Change {
+	var out []Change
+	for p, st := range s.files {
+		old, ok := before.files[p]
+		switch {
+		case !ok:
+			out = append(out, Change{Kind: Created, Path: p})
+		case old.size != st.size || !old.modTime.Equal(st.modTime):
+			out = append(out, Change{Kind: Modified, Path: p})
+		}
+	}
+	for p := range before.files {
+		if _, ok := s.files[p]; !ok && !s.Partial && !before.Partial {
+			out = append(out, Change{Kind: Removed, Path: p})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
@@ -0,0 +1,80 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("keep.txt", "same")
+	write("src/edit.go", "package a")
+	write("touch.txt", "same size")
+	write("gone.txt", "bye")
+	write(".git/HEAD", "ref")
+	write("node_modules/x/index.js", "x")
+	before := TakeSnapshot(dir)
+	if len(before.files) != 4 {
+		t.Fatalf("snapshot has %d files, want 4 outside .git and node_modules: %v", len(before.files), before)
+	}
+
+	write("src/edit.go", "package b // longer")
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "touch.txt"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	write("new/file.md", "hi")
+	write(".git/index", "ignored")
+
+	got := TakeSnapshot(dir).Diff(before)
+	want := []Change{
+		{Removed, "gone.txt"},
+		{Created, filepath.Join("new", "file.md")},
+		{Modified, filepath.Join("src", "edit.go")},
+		{Modified, "touch.txt"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Diff = %v, want %v", got, want)
+	}
+}
+
+func TestTakeSnapshotStopsAtTheLimit(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := takeSnapshot(dir, 3)
+	if len(before.files) != 3 || !before.Partial {
+		t.Errorf("snapshot has %d files, partial %v; want 3, partial", len(before.files), before.Partial)
+	}
+	if snap := takeSnapshot(dir, 5); snap.Partial {
+		t.Error("a snapshot of every file isn't partial")
+	}
+
+	// A file that drops out past the limit isn't reported as removed.
+	if err := os.WriteFile(filepath.Join(dir, "0"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := takeSnapshot(dir, 3).Diff(before)
+	if want := []Change{{Created, "0"}}; !slices.Equal(got, want) {
+		t.Errorf("Diff = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"vibepup-tui/process"
+	"vibepup-tui/session"
+)
+
+// What to do with an active runner on quitting.
+const (
+	quitStop   = "stop"
+	quitFinish = "finish"
+	quitDetach = "detach"
+)
+
+// quit leaves the TUI, first asking what should happen to the runner if
+// one is going. Quitting while a stop is under way kills it outright.
+func (m *model) quit() tea.Cmd {
+	if m.runner == nil {
+		return tea.Quit
+	}
+	if m.stopping {
+		m.runner.Kill() // ZOMBIE KILLER
+		return tea.Quit
+	}
+	return m.openQuit()
+}
+
+func (m *model) openQuit() tea.Cmd {
+	where := "It hasn't started a loop yet."
+	if m.iteration > 0 {
+		where = fmt.Sprintf("It's on loop %d (%s).", m.iteration, m.phase)
+	}
+	quitKey := m.keymaps.For(m.keyState()).Quit.Help().Key
+
+	keymap := huh.NewDefaultKeyMap()
+	keymap.Quit = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "keep running"))
+	m.quitPick = new(string)
+	m.quitForm = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("The runner is still going").
+				Description(where+" Press "+quitKey+" again to kill it now.").
+				Options(
+					huh.NewOption("Stop it gracefully, then quit", quitStop),
+					huh.NewOption("Let this iteration finish, then stop and quit", quitFinish),
+					huh.NewOption("Detach: quit and leave it running", quitDetach),
+				).
+				Value(m.quitPick),
+		),
+	).WithTheme(huh.ThemeDracula()).WithKeyMap(keymap)
+	m.prevState = m.state
+	m.state = stateQuit
+	return m.quitForm.Init()
+}
+
+func (m model) updateQuit(msg tea.Msg) (model, tea.Cmd) {
+	if k, ok := msg.(tea.KeyMsg); ok && key.Matches(k, m.keymaps.For(m.prevState).Quit) {
+		if m.runner != nil {
+			m.runner.Kill() // ZOMBIE KILLER
+		}
+		return m, tea.Quit
+	}
+	form, cmd := m.quitForm.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.quitForm = f
+	}
+	switch m.quitForm.State {
+	case huh.StateCompleted:
+		m.state = m.prevState
+		switch *m.quitPick {
+		case quitStop:
+			m.quitOnExit = true
+			m.stop()
+		case quitFinish:
+			m.quitOnExit = true
+			m.stopAfterIteration()
+		case quitDetach:
+			cmd := m.detach()
+			return m, cmd
+		}
+	case huh.StateAborted:
+		m.state = m.prevState
+	}
+	return m, cmd
+}
+
+// stop walks the runner up the signal ladder; its exit brings up the
+// summary screen, or quits if that was asked for.
+func (m *model) stop() {
+	m.viewport.WriteNote("--- Stopping runner ---")
+	m.stopping = true
+	m.runner.Stop(process.StopStep)
+}
+
+// stopAfterIteration stops the runner as soon as the next loop starts, so
+// the one under way gets to finish.
+func (m *model) stopAfterIteration() {
+	m.stopAtLoop = true
+	if m.iteration > 0 {
+		m.viewport.WriteNote(fmt.Sprintf("--- Stopping after loop %d ---", m.iteration))
+	} else {
+		m.viewport.WriteNote("--- Stopping before the first loop ---")
+	}
+}
+
+// detach quits and leaves the runner going. A supervisor is started to
+// take over its output, keep the session log and let a later TUI attach;
+// one attached already just carries on.
+func (m *model) detach() tea.Cmd {
+	if m.attached != nil {
+		m.detached = m.attached
+		return tea.Quit
+	}
+	meta := session.Meta{
+		PID:       m.runner.Pid(),
+		Runner:    runnerCommand(m.cfg),
+		Args:      m.run.Args,
+		Started:   m.runStarted,
+		Detached:  time.Now(),
+		Iteration: m.iteration,
+		Log:       m.viewport.Store().Path(),
+	}
+	if meta.Log == "" {
+		m.viewport.WriteNote("Can't detach: there's no session log to keep the output in.")
+		return nil
+	}
+	exe, err := os.Executable()
+	if err == nil {
+		err = session.Save(m.projectDir, meta)
+	}
+	if err == nil {
+		adopt := exec.Command(exe, session.AdoptCommand)
+		adopt.Dir = m.projectDir
+		if err = m.runner.Detach(adopt); err != nil {
+			os.Remove(session.Path(m.projectDir))
+		}
+	}
+	if err != nil {
+		m.viewport.WriteNote("Detach failed: " + err.Error())
+		return nil
+	}
+	m.detached = &meta
+	return tea.Quit
+}
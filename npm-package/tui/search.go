@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	searchRegexKey  = key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "regex"))
+	searchCommitKey = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "done"))
+	searchCancelKey = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear"))
+)
+
+func newSearchInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.Placeholder = "search the whole log"
+	ti.CharLimit = 256
+	return ti
+}
+
+// startSearch opens the search prompt, seeded with the active query.
+func (m *model) startSearch() tea.Cmd {
+	query, regex := m.viewport.SearchQuery()
+	m.searchInput.SetValue(query)
+	m.searchInput.CursorEnd()
+	m.searchRegex = regex
+	m.searchTyping = true
+	m.searchErr = nil
+	return m.searchInput.Focus()
+}
+
+// searchDelay is how long typing has to pause before the search runs, so
+// a long session isn't searched again on every keystroke.
+const searchDelay = 150 * time.Millisecond
+
+// searchMsg runs the search typed so far, unless more was typed since.
+type searchMsg struct {
+	seq int
+}
+
+// updateSearchInput handles keys while the search prompt is focused. The
+// search runs as you type, once typing pauses, so the counter and
+// highlights stay current.
+func (m model) updateSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, searchCommitKey):
+		m.searchTyping = false
+		m.searchInput.Blur()
+		m.runSearch()
+		return m, nil
+	case key.Matches(msg, searchCancelKey):
+		m.searchTyping = false
+		m.searchInput.Blur()
+		m.searchErr = nil
+		m.searchSeq++
+		m.viewport.ClearSearch()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	if key.Matches(msg, searchRegexKey) {
+		m.searchRegex = !m.searchRegex
+	} else {
+		m.searchInput, cmd = m.searchInput.Update(msg)
+	}
+	m.searchSeq++
+	seq := m.searchSeq
+	return m, tea.Batch(cmd, tea.Tick(searchDelay, func(time.Time) tea.Msg { return searchMsg{seq} }))
+}
+
+// runSearch searches for what's in the prompt, dropping any search still
+// waiting for typing to pause.
+func (m *model) runSearch() {
+	m.searchSeq++
+	m.searchErr = m.viewport.SetSearch(m.searchInput.Value(), m.searchRegex)
+}
+
+// searchBar renders the prompt or, once committed, the active query with its
+// match counter.
+func (m model) searchBar() string {
+	if !m.searchTyping && !m.viewport.Searching() {
+		return ""
+	}
+	muted := lipgloss.NewStyle().Foreground(m.theme.Muted)
+	accent := lipgloss.NewStyle().Foreground(m.theme.Highlight)
+
+	bar := m.searchInput.View()
+	if !m.searchTyping {
+		query, _ := m.viewport.SearchQuery()
+		bar = accent.Render("/" + query)
+	}
+	if m.searchRegex {
+		bar += muted.Render(" [regex]")
+	}
+	cur, total := m.viewport.MatchStatus()
+	bar += "  " + accent.Render(fmt.Sprintf("%d/%d", cur, total))
+	if m.searchErr != nil {
+		bar += "  " + lipgloss.NewStyle().Foreground(m.theme.Accent).Render(m.searchErr.Error())
+	}
+	if m.searchTyping {
+		return bar + "  " + muted.Render(m.help.ShortHelpView([]key.Binding{searchCommitKey, searchRegexKey, searchCancelKey}))
+	}
+	return bar + "  " + muted.Render(m.help.ShortHelpView([]key.Binding{m.keys().SearchNext, m.keys().SearchPrev, searchCancelKey}))
+}
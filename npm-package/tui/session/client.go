@@ -0,0 +1,94 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"vibepup-tui/process"
+)
+
+// Client is a TUI's connection to the project's supervisor.
+type Client struct {
+	// Meta and Paused describe the session as of attaching.
+	Meta   Meta
+	Paused bool
+	// Replay is how many of the first lines are replayed scrollback.
+	Replay int
+
+	conn net.Conn
+	dec  *json.Decoder
+	mu   sync.Mutex
+	enc  *json.Encoder
+}
+
+// Dial connects to the project's supervisor.
+func Dial(dir string) (*Client, error) {
+	conn, err := net.Dial("unix", SocketPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	return newClient(conn)
+}
+
+func newClient(conn net.Conn) (*Client, error) {
+	c := &Client{conn: conn, dec: json.NewDecoder(conn), enc: json.NewEncoder(conn)}
+	var hello Event
+	if err := c.dec.Decode(&hello); err != nil || hello.Type != EventHello || hello.Meta == nil {
+		conn.Close()
+		return nil, errors.New("the supervisor didn't say hello")
+	}
+	c.Meta, c.Paused, c.Replay = *hello.Meta, hello.Paused, hello.Replay
+	return c, nil
+}
+
+// Send passes a command to the supervisor, making Client a
+// process.Control.
+func (c *Client) Send(cmd string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enc.Encode(Command{Cmd: cmd})
+}
+
+// Runner returns a stand-in for the supervisor's runner, fed with the
+// replayed scrollback and then the live output, and the command that
+// reports its exit.
+func (c *Client) Runner() (*process.Runner, tea.Cmd) {
+	r := process.Remote(c.Meta.PID, c.Paused, c)
+	exited := make(chan error, 1)
+	go func() {
+		defer close(r.OutputChan)
+		for {
+			var ev Event
+			if err := c.dec.Decode(&ev); err != nil {
+				exited <- fmt.Errorf("lost the supervisor: %w", err)
+				return
+			}
+			switch ev.Type {
+			case EventLine:
+				r.OutputChan <- ev.Text
+			case EventExit:
+				var err error
+				if ev.Code != 0 || ev.Error != "" {
+					err = &process.ExitError{Code: ev.Code, Msg: ev.Error}
+				}
+				exited <- err
+				return
+			}
+		}
+	}()
+	return r, func() tea.Msg {
+		err := <-exited
+		c.conn.Close()
+		return r.Done(err)
+	}
+}
+
+// Close disconnects, leaving the runner going.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,55 @@
+// Package session keeps track of runners that outlive the TUI that started
+// them, so a later TUI can find them again.
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"vibepup-tui/internal/atomicfile"
+)
+
+// Meta describes a runner left going by a detached TUI.
+type Meta struct {
+	PID       int       `json:"pid"`
+	Runner    string    `json:"runner"`
+	Args      []string  `json:"args"`
+	Started   time.Time `json:"started"`
+	Detached  time.Time `json:"detached"`
+	Ended     time.Time `json:"ended,omitzero"`
+	Iteration int       `json:"iteration"`
+	// Log is the session log the runner's output is appended to.
+	Log string `json:"log"`
+}
+
+// Path is where the project's detached session is recorded.
+func Path(dir string) string {
+	return filepath.Join(dir, ".ralph", "tui", "detached.json")
+}
+
+// Save records m as the project's detached session.
+func Save(dir string, m Meta) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(Path(dir), append(data, '\n'))
+}
+
+// Load reads the project's detached session.
+func Load(dir string) (Meta, error) {
+	var m Meta
+	data, err := os.ReadFile(Path(dir))
+	if err != nil {
+		return m, err
+	}
+	return m, json.Unmarshal(data, &m)
+}
+
+// Running reports whether the runner is still going.
+func (m Meta) Running() bool {
+	return m.Ended.IsZero() && m.PID > 0 && syscall.Kill(m.PID, 0) == nil
+}
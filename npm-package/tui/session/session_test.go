@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"vibepup-tui/process"
+	"vibepup-tui/summary"
+	"vibepup-tui/ui"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	want := Meta{PID: os.Getpid(), Runner: "vibepup", Args: []string{"--watch"}, Started: time.Now().Round(time.Second), Log: "session.log"}
+	if err := Save(dir, want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.PID != want.PID || !got.Started.Equal(want.Started) || strings.Join(got.Args, " ") != "--watch" {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+	if !got.Running() {
+		t.Error("a live PID without an end time should be running")
+	}
+	got.Ended = time.Now()
+	if got.Running() {
+		t.Error("an ended session shouldn't be running")
+	}
+}
+
+func TestSupervisorReplaysAndTakesCommands(t *testing.T) {
+	dir := t.TempDir()
+	runner, done := process.Start(context.Background(), "sh", []string{"-c", "echo one; echo warn >&2; exec sleep 30"}, nil)
+	if runner == nil {
+		t.Fatalf("runner didn't start: %v", done())
+	}
+	store := ui.NewLineStore(100, filepath.Join(dir, "session.log"))
+	defer store.Close()
+	meta := Meta{PID: runner.Pid(), Runner: "sh", Started: time.Now(), Log: store.Path()}
+	if err := Save(dir, meta); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := Listen(dir)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	sup := &Supervisor{Dir: dir, Meta: meta, Runner: runner, Store: store, Replay: 100}
+	served := make(chan error)
+	go func() { served <- sup.Serve(ln, done) }()
+
+	first, err := Dial(dir)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if first.Meta.PID != runner.Pid() {
+		t.Errorf("hello PID = %d, want %d", first.Meta.PID, runner.Pid())
+	}
+	r1, done1 := first.Runner()
+	got := map[string]bool{<-r1.OutputChan: true, <-r1.OutputChan: true}
+	if !got["one"] || !got["ERR: warn"] {
+		t.Fatalf("first client got %v", got)
+	}
+
+	// A client attaching later is sent the scrollback first.
+	second, err := Dial(dir)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	r2, done2 := second.Runner()
+	replayed := map[string]bool{<-r2.OutputChan: true, <-r2.OutputChan: true}
+	if !replayed["one"] || !replayed["ERR: warn"] {
+		t.Fatalf("second client replayed %v", replayed)
+	}
+
+	r2.Kill()
+	for _, done := range []tea.Cmd{done1, done2} {
+		msg := done().(process.DoneMsg)
+		if code := summary.ExitCode(msg.Err); code != 137 {
+			t.Errorf("exit code = %d (%v), want 137", code, msg.Err)
+		}
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	if m, _ := Load(dir); m.Ended.IsZero() || m.Running() {
+		t.Error("Serve should record when the runner ended")
+	}
+	if _, err := os.Stat(SocketPath(dir)); !os.IsNotExist(err) {
+		t.Errorf("socket should be gone once the runner ends: %v", err)
+	}
+}
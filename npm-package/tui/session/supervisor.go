@@ -0,0 +1,270 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"vibepup-tui/internal/sockfile"
+	"vibepup-tui/process"
+	"vibepup-tui/summary"
+	"vibepup-tui/ui"
+)
+
+// Hidden subcommands that run a supervisor in the background.
+const (
+	// SuperviseCommand starts the runner and supervises it:
+	// "vibepup-tui [flags] __supervise [runner args]".
+	SuperviseCommand = "__supervise"
+	// AdoptCommand supervises the runner a detaching TUI hands over on
+	// file descriptors 3 and 4.
+	AdoptCommand = "__adopt"
+)
+
+// clientQueue is how many events a client may fall behind by before it's
+// dropped.
+const clientQueue = 4096
+
+// SocketPath is where the project's supervisor listens.
+func SocketPath(dir string) string {
+	return filepath.Join(dir, ".ralph", "daemon.sock")
+}
+
+// Event is a message from a supervisor to a client, sent as one JSON
+// object per line.
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Text is the output line of a line event.
+	Text string `json:"text,omitempty"`
+	// Meta and Paused describe the session in the hello event.
+	Meta   *Meta `json:"meta,omitempty"`
+	Paused bool  `json:"paused,omitempty"`
+	// Replay is how many lines of scrollback follow the hello event.
+	Replay int `json:"replay,omitempty"`
+	// Code and Error report the runner's exit in the exit event.
+	Code  int    `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Event types. A client is sent hello, the recent scrollback as line
+// events, then live lines until exit.
+const (
+	EventHello = "hello"
+	EventLine  = "line"
+	EventExit  = "exit"
+)
+
+// Command is a message from a client: one of the process.Control commands.
+type Command struct {
+	Cmd string `json:"cmd"`
+}
+
+// Supervisor owns a runner on behalf of the TUIs that attach to it. It
+// keeps the session log, serves the output and takes commands back, so
+// the run doesn't depend on any terminal staying open.
+type Supervisor struct {
+	Dir    string
+	Meta   Meta
+	Runner *process.Runner
+	Store  *ui.LineStore
+	// Replay is how many recent lines a client is sent on attaching.
+	Replay int
+	// Watch, if set, is given each output line and then the runner's exit,
+	// e.g. to notify of the run whether or not a TUI is attached.
+	Watch func(line string)
+	Exit  func(err error)
+
+	mu      sync.Mutex
+	clients map[*client]bool
+	exit    *Event
+	writers sync.WaitGroup
+}
+
+type client struct {
+	conn    net.Conn
+	backlog []Event
+	out     chan Event
+}
+
+// Listen opens the project's supervisor socket, clearing one left behind
+// by a supervisor that's gone.
+func Listen(dir string) (net.Listener, error) {
+	path := SocketPath(dir)
+	ln, err := sockfile.Listen(path)
+	if errors.Is(err, sockfile.ErrInUse) {
+		return nil, fmt.Errorf("a supervisor is already listening on %s", path)
+	}
+	return ln, err
+}
+
+// Serve logs and serves the runner's output until it exits, then tells
+// every client, closes ln and records the end of the session. done is the
+// runner's exit command. With a nil ln the output is only logged.
+func (s *Supervisor) Serve(ln net.Listener, done tea.Cmd) error {
+	s.clients = map[*client]bool{}
+	if ln != nil {
+		go s.accept(ln)
+	}
+	exited := make(chan process.DoneMsg, 1)
+	go func() {
+		msg, _ := done().(process.DoneMsg)
+		exited <- msg
+	}()
+
+	for line := range s.Runner.OutputChan {
+		s.mu.Lock()
+		s.Store.Append(line)
+		s.broadcast(Event{Type: EventLine, Time: time.Now(), Text: line})
+		s.mu.Unlock()
+		if s.Watch != nil {
+			s.Watch(line)
+		}
+	}
+	msg := <-exited
+	if s.Exit != nil {
+		s.Exit(msg.Err)
+	}
+
+	ev := Event{Type: EventExit, Time: time.Now(), Code: summary.ExitCode(msg.Err)}
+	if msg.Err != nil {
+		ev.Error = msg.Err.Error()
+	}
+	s.mu.Lock()
+	s.Store.Append("--- Process Finished ---")
+	s.exit = &ev
+	s.broadcast(ev)
+	for c := range s.clients {
+		close(c.out)
+	}
+	s.clients = nil
+	s.mu.Unlock()
+	if ln != nil {
+		ln.Close()
+	}
+	s.writers.Wait()
+
+	if cur, err := Load(s.Dir); err == nil && cur.PID == s.Meta.PID {
+		s.Meta = cur
+	}
+	s.Meta.Ended = ev.Time
+	return Save(s.Dir, s.Meta)
+}
+
+func (s *Supervisor) accept(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		s.attach(conn)
+	}
+}
+
+// attach greets a new client with the session and the recent scrollback,
+// then registers it for live lines. Both happen under the lock, so no line
+// is missed or sent twice.
+func (s *Supervisor) attach(conn net.Conn) {
+	c := &client{conn: conn, out: make(chan Event, clientQueue)}
+	s.mu.Lock()
+	meta := s.Meta
+	from := max(s.Store.First(), s.Store.Total()-s.Replay)
+	c.backlog = append(c.backlog, Event{Type: EventHello, Time: time.Now(), Meta: &meta, Paused: s.Runner.Paused(), Replay: s.Store.Total() - from})
+	for i := from; i < s.Store.Total(); i++ {
+		c.backlog = append(c.backlog, Event{Type: EventLine, Time: s.Store.Time(i), Text: s.Store.Line(i)})
+	}
+	if s.exit != nil {
+		c.backlog = append(c.backlog, *s.exit)
+		close(c.out)
+	} else {
+		s.clients[c] = true
+	}
+	s.writers.Add(1)
+	s.mu.Unlock()
+
+	go s.write(c)
+	go s.read(c)
+}
+
+func (s *Supervisor) write(c *client) {
+	defer s.writers.Done()
+	defer c.conn.Close()
+	enc := json.NewEncoder(c.conn)
+	for _, ev := range c.backlog {
+		if enc.Encode(ev) != nil {
+			s.drop(c)
+			return
+		}
+	}
+	c.backlog = nil
+	for ev := range c.out {
+		if enc.Encode(ev) != nil {
+			s.drop(c)
+			return
+		}
+	}
+}
+
+func (s *Supervisor) read(c *client) {
+	dec := json.NewDecoder(c.conn)
+	for {
+		var cmd Command
+		if dec.Decode(&cmd) != nil {
+			return
+		}
+		s.control(cmd.Cmd)
+	}
+}
+
+// broadcast queues ev for every client; one too far behind is dropped
+// rather than holding up the runner. Called with the lock held.
+func (s *Supervisor) broadcast(ev Event) {
+	for c := range s.clients {
+		select {
+		case c.out <- ev:
+		default:
+			delete(s.clients, c)
+			close(c.out)
+		}
+	}
+}
+
+func (s *Supervisor) drop(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clients[c] {
+		delete(s.clients, c)
+		close(c.out)
+	}
+}
+
+// control carries out a client's command. Failures are logged and sent to
+// every client, since any of them may be watching.
+func (s *Supervisor) control(cmd string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	switch cmd {
+	case "pause":
+		err = s.Runner.Pause()
+	case "resume":
+		err = s.Runner.Resume()
+	case "stop":
+		s.Runner.Stop(process.StopStep)
+	case "kill":
+		s.Runner.Kill()
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		line := fmt.Sprintf("Supervisor: %s failed: %v", cmd, err)
+		s.Store.Append(line)
+		s.broadcast(Event{Type: EventLine, Time: time.Now(), Text: line})
+	}
+}
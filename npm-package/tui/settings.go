@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+
+	"vibepup-tui/animations"
+	"vibepup-tui/config"
+	"vibepup-tui/motion"
+	"vibepup-tui/persona"
+	"vibepup-tui/theme"
+)
+
+// settingsDraft holds the values bound to the settings form. It lives on the
+// heap so the form's pointers survive the model being copied by Bubble Tea.
+type settingsDraft struct {
+	Theme    string
+	Snark    string
+	Anim     string
+	FX       string
+	Colors   bool
+	Quiet    bool
+	PerfLow  bool
+	Design   bool
+	MaxTurn  string
+	NoOutput string
+	Target   string // "user" or "project"
+}
+
+func newSettingsDraft(cfg config.Config) *settingsDraft {
+	return &settingsDraft{
+		Theme:    cfg.Theme,
+		Snark:    cfg.Snark,
+		Anim:     cfg.Anim,
+		FX:       cfg.FX,
+		Colors:   cfg.Log.ThemeColors,
+		Quiet:    cfg.Quiet,
+		PerfLow:  cfg.PerfLow,
+		Design:   cfg.Design,
+		MaxTurn:  strconv.Itoa(cfg.Watchdog.MaxTurnSeconds),
+		NoOutput: strconv.Itoa(cfg.Watchdog.NoOutputSeconds),
+		Target:   "user",
+	}
+}
+
+// values returns the draft keyed by config setting name.
+func (d *settingsDraft) values() map[string]any {
+	maxTurn, _ := strconv.Atoi(d.MaxTurn)
+	noOutput, _ := strconv.Atoi(d.NoOutput)
+	return map[string]any{
+		"theme":                      d.Theme,
+		"snark":                      d.Snark,
+		"anim":                       d.Anim,
+		"fx":                         d.FX,
+		"log.theme_colors":           d.Colors,
+		"quiet":                      d.Quiet,
+		"perf_low":                   d.PerfLow,
+		"design":                     d.Design,
+		"watchdog.max_turn_seconds":  maxTurn,
+		"watchdog.no_output_seconds": noOutput,
+	}
+}
+
+func seconds(key string) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("enter a number of seconds")
+		}
+		return config.Check(key, n)
+	}
+}
+
+func newSettingsForm(d *settingsDraft, dir string) *huh.Form {
+	snarks := make([]string, 0, 3)
+	for _, l := range persona.Levels() {
+		snarks = append(snarks, string(l))
+	}
+
+	keymap := huh.NewDefaultKeyMap()
+	keymap.Quit = key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "cancel"))
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().Title("Theme").Options(huh.NewOptions(theme.Names()...)...).Value(&d.Theme),
+			huh.NewSelect[string]().Title("Snark level").Options(huh.NewOptions(snarks...)...).Value(&d.Snark),
+			huh.NewSelect[string]().Title("Animation preset").Options(huh.NewOptions(animations.Names()...)...).Value(&d.Anim),
+			huh.NewSelect[string]().Title("Header effect").Options(huh.NewOptions(motion.Effects()...)...).Value(&d.FX),
+			huh.NewConfirm().Title("Theme the runner's colours").Value(&d.Colors),
+		).Title("Look & feel"),
+		huh.NewGroup(
+			huh.NewConfirm().Title("Quiet (less motion and chatter)").Value(&d.Quiet),
+			huh.NewConfirm().Title("Low performance mode").Value(&d.PerfLow),
+			huh.NewConfirm().Title("Design mode").Description("Inject the frontend-design skill on the next run").Value(&d.Design),
+		).Title("Behaviour"),
+		huh.NewGroup(
+			huh.NewInput().Title("Max turn seconds").Value(&d.MaxTurn).Validate(seconds("watchdog.max_turn_seconds")),
+			huh.NewInput().Title("No-output seconds").Value(&d.NoOutput).Validate(seconds("watchdog.no_output_seconds")),
+		).Title("Watchdog").Description("Applies from the next run"),
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Save to").
+				Options(
+					huh.NewOption("User ("+config.UserPath()+")", "user"),
+					huh.NewOption("Project ("+config.ProjectPath(dir)+")", "project"),
+				).
+				Value(&d.Target),
+		),
+	).WithTheme(huh.ThemeDracula()).WithKeyMap(keymap)
+}
+
+// openSettings shows the settings form populated from the effective config.
+func (m *model) openSettings() tea.Cmd {
+	m.settingsDraft = newSettingsDraft(m.cfg)
+	m.settings = newSettingsForm(m.settingsDraft, m.projectDir)
+	m.prevState = m.state
+	m.state = stateSettings
+	return m.settings.Init()
+}
+
+func (m model) updateSettings(msg tea.Msg) (model, tea.Cmd) {
+	form, cmd := m.settings.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.settings = f
+	}
+	switch m.settings.State {
+	case huh.StateCompleted:
+		m.state = m.prevState
+		m.saveSettings()
+		m.resize()
+	case huh.StateAborted:
+		m.state = m.prevState
+	}
+	return m, cmd
+}
+
+// saveSettings applies changed values to the running TUI and writes them to
+// the chosen config file, leaving the rest of the file untouched.
+func (m *model) saveSettings() {
+	path, label := config.UserPath(), "user"
+	if m.settingsDraft.Target == "project" {
+		path, label = config.ProjectPath(m.projectDir), "project"
+	}
+
+	changed := map[string]any{}
+	for k, v := range m.settingsDraft.values() {
+		if m.cfg.Get(k) != v {
+			changed[k] = v
+		}
+	}
+	if len(changed) == 0 {
+		m.viewport.WriteNote("--- Settings unchanged ---")
+		return
+	}
+
+	src := config.FileSource(label, path)
+	for k, v := range changed {
+		if m.cfg.Overridden(k) {
+			m.viewport.WriteNote(fmt.Sprintf("Note: %s is set by %s, which overrides %s on the next start", k, m.cfg.Source(k), path))
+		}
+		_ = m.cfg.Set(k, v, src)
+	}
+	m.applyLiveSettings()
+
+	if err := config.UpdateFile(path, changed); err != nil {
+		m.viewport.WriteNote(fmt.Sprintf("Error saving settings: %v", err))
+		return
+	}
+	m.viewport.WriteNote(fmt.Sprintf("--- Saved %d setting(s) to %s ---", len(changed), path))
+}
+
+// applyLiveSettings pushes the config into the parts of the TUI that can
+// change without a restart: theme, persona and motion engine.
+func (m *model) applyLiveSettings() {
+	m.theme = theme.Get(m.cfg.Theme)
+	m.spinner.Style = lipgloss.NewStyle().Foreground(m.theme.Highlight)
+	m.viewport.Theme = m.theme
+	m.viewport.Palette = m.logPalette()
+	m.snark = persona.ParseSnark(m.cfg.Snark)
+	m.motion.Quiet = m.cfg.Quiet
+	m.motion.LowPerf = m.cfg.PerfLow
+	m.motion.SetEffect(m.cfg.FX, effectPalette(m.theme), m.width-4)
+}
+
+func (m model) settingsView() string {
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().Foreground(m.theme.Accent).Render("SETTINGS"),
+		m.settings.View(),
+	)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"vibepup-tui/ui"
+)
+
+// runnerDefaultIterations is how many loops the runner does when not
+// given a count (DEFAULT_ITERATIONS in lib/runner/index.js).
+const runnerDefaultIterations = 5
+
+// iterationLimit works out the runner's loop limit from its arguments the
+// way the runner parses them; 0 means unlimited (watch mode).
+func iterationLimit(args []string) int {
+	limit := runnerDefaultIterations
+	for _, a := range args {
+		if a == "--watch" {
+			return 0
+		}
+		if n, err := strconv.Atoi(a); err == nil && n >= 0 && strings.Trim(a, "0123456789") == "" {
+			limit = n
+		}
+	}
+	return limit
+}
+
+// statusSegments describes the run for the status bar, most important
+// first in priority.
+func (m model) statusSegments(now time.Time) []ui.Segment {
+	phase := "idle"
+	switch {
+	case m.stopping:
+		phase = "stopping"
+	case m.runner != nil && m.phase != "":
+		phase = strings.ToUpper(m.phase)
+	case m.runner != nil:
+		phase = "starting"
+	case m.state == stateDone:
+		phase = "done"
+	}
+	if m.stopAtLoop && !m.stopping {
+		phase += " (last loop)"
+	}
+	segs := []ui.Segment{{Text: phase, Priority: 9, Strong: true}}
+
+	if m.iteration > 0 {
+		limit := "∞"
+		if m.asciiOnly() {
+			limit = "inf"
+		}
+		if m.iterLimit > 0 {
+			limit = strconv.Itoa(m.iterLimit)
+		}
+		segs = append(segs, ui.Segment{Text: fmt.Sprintf("loop %d/%s", m.iteration, limit), Priority: 8})
+	}
+	if m.model != "" {
+		segs = append(segs, ui.Segment{Text: m.model, Priority: 3})
+	}
+	if m.runner != nil && !m.turnStarted.IsZero() {
+		segs = append(segs, ui.Segment{Text: "turn " + ui.FormatDuration(now.Sub(m.turnStarted).Truncate(time.Second)), Priority: 6})
+		if left, ok := m.watchdogLeft(now); ok {
+			segs = append(segs, ui.Segment{Text: "watchdog " + ui.FormatDuration(left), Priority: 5})
+		}
+	}
+	if m.tasks.Total() > 0 {
+		segs = append(segs, ui.Segment{Text: fmt.Sprintf("tasks %d/%d", m.tasks.Done(), m.tasks.Total()), Priority: 7})
+	}
+	if m.runner != nil {
+		if pid := m.runner.Pid(); pid > 0 {
+			segs = append(segs, ui.Segment{Text: fmt.Sprintf("pid %d", pid), Priority: 1})
+		}
+	}
+	return segs
+}
+
+// watchdogLeft returns how long until the runner's watchdog kills the
+// turn: whichever of the turn cap and the silence limit comes first.
+func (m model) watchdogLeft(now time.Time) (time.Duration, bool) {
+	w := m.cfg.Watchdog
+	if w.MaxTurnSeconds <= 0 || w.NoOutputSeconds <= 0 {
+		return 0, false
+	}
+	left := time.Duration(w.MaxTurnSeconds)*time.Second - now.Sub(m.turnStarted)
+	if !m.lastOutput.IsZero() {
+		left = min(left, time.Duration(w.NoOutputSeconds)*time.Second-now.Sub(m.lastOutput))
+	}
+	return max(left, 0).Truncate(time.Second), true
+}
+
+// statusBar renders the status bar across the screen.
+func (m model) statusBar() string {
+	return ui.StatusBar{Theme: m.theme}.Render(m.statusSegments(time.Now()), m.width)
+}
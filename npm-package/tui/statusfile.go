@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"vibepup-tui/config"
+	"vibepup-tui/control"
+)
+
+// statusFile keeps .ralph/status.json in step with the TUI, so a tmux
+// status bar or a shell prompt can show a project without opening it.
+type statusFile struct {
+	dir  string
+	last control.Status
+	// failed stops retrying a file that can't be written, e.g. in a
+	// read-only checkout; the TUI carries on regardless.
+	failed bool
+}
+
+// update writes s if anything in it has changed since the last write.
+func (f *statusFile) update(s control.Status) {
+	if f == nil || f.failed || s == f.last {
+		return
+	}
+	f.last = s
+	s.Updated = time.Now()
+	if err := control.WriteStatus(f.dir, s); err != nil {
+		f.failed = true
+	}
+}
+
+// exit records the TUI quitting, and whether it left a runner going.
+func (f *statusFile) exit(m model) {
+	if f == nil {
+		return
+	}
+	s := m.status()
+	s.Run, s.Running, s.Paused, s.Stopping, s.Waiting = control.RunExited, false, false, false, false
+	if m.detached != nil {
+		s.Run, s.PID = control.RunDetached, m.detached.PID
+	}
+	f.update(s)
+}
+
+// statusLine prints the status of the project in dir, or in the directory
+// given as the only argument, as one line. A project without a status
+// file prints nothing, so prompts stay clean outside vibepup projects.
+func statusLine(dir string, cfg config.Config) error {
+	if len(cfg.Args) > 1 {
+		return errors.New("usage: vibepup-tui --status-line [project dir]")
+	}
+	if len(cfg.Args) == 1 {
+		dir = cfg.Args[0]
+	}
+	s, err := control.ReadStatus(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// A TUI that was killed couldn't say so; its runner went with it.
+	if s.Running && s.PID > 0 && syscall.Kill(s.PID, 0) == syscall.ESRCH {
+		s.Run, s.Running = control.RunExited, false
+	}
+	line := s.Line(cfg.NoEmoji)
+	if len(cfg.Args) == 1 {
+		line = filepath.Base(filepath.Clean(dir)) + " " + line
+	}
+	fmt.Println(line)
+	return nil
+}
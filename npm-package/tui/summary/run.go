@@ -0,0 +1,255 @@
+package summary
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"vibepup-tui/events"
+)
+
+// Outcomes of a model's turn.
+const (
+	OK          = "ok"
+	Failed      = events.Failed
+	Unsupported = events.Unsupported
+	// Interrupted turns were cut short by the runner exiting.
+	Interrupted = "interrupted"
+)
+
+// Outcomes of a run, as scripts see them.
+const (
+	// OutcomeComplete is the agent reporting the PRD done.
+	OutcomeComplete = "complete"
+	// OutcomeMaxIterations is the runner using up its loops without that.
+	OutcomeMaxIterations = "max_iterations"
+	// OutcomeModelsFailed is every model failing the last iteration.
+	OutcomeModelsFailed = "all_models_failed"
+	// OutcomeWatchdog is the last iteration failing with its final turn
+	// killed by the watchdog.
+	OutcomeWatchdog = "watchdog"
+	// OutcomeStopped is the runner stopped by a signal.
+	OutcomeStopped = "stopped"
+	// OutcomeError is the runner failing by itself.
+	OutcomeError = "error"
+)
+
+// Attempt is one model's turn in an iteration.
+type Attempt struct {
+	Iteration int
+	Model     string
+	Outcome   string // empty while the turn is running
+	ExitCode  int
+}
+
+// Run is what happened during one runner process, built from its output.
+type Run struct {
+	Args       []string
+	Started    time.Time
+	Ended      time.Time
+	Iterations int
+	Attempts   []Attempt
+	// Completed is set when the agent reported the PRD done.
+	Completed bool
+	// LogDirs are the iteration directories the runner logged to.
+	LogDirs       []string
+	WatchdogKills int
+	// Exhausted counts the iterations every model failed.
+	Exhausted int
+	ExitCode  int
+	Err       error
+
+	exhaustedLast bool // the latest iteration was exhausted
+	watchdogLast  bool // and its final turn was killed by the watchdog
+}
+
+// New starts a run record.
+func New(args []string, at time.Time) Run {
+	return Run{Args: args, Started: at}
+}
+
+// Feed records a runner event.
+func (r *Run) Feed(ev events.Event) {
+	switch ev.Kind {
+	case events.Loop:
+		r.settle(OK)
+		r.Iterations = max(r.Iterations, ev.Iteration)
+		r.exhaustedLast = false
+	case events.Model:
+		r.settle(OK)
+		r.Attempts = append(r.Attempts, Attempt{Iteration: r.Iterations, Model: ev.Model})
+	case events.Result:
+		if a := r.open(); a != nil && a.Model == ev.Model {
+			a.Outcome, a.ExitCode = ev.Result, ev.ExitCode
+		}
+	case events.Complete:
+		r.settle(OK)
+		r.Completed = true
+	case events.Logs:
+		r.LogDirs = append(r.LogDirs, ev.Path)
+	case events.Exhausted:
+		r.Exhausted++
+		r.exhaustedLast = true
+	}
+}
+
+// open returns the turn still in progress, if any.
+func (r *Run) open() *Attempt {
+	if n := len(r.Attempts); n > 0 && r.Attempts[n-1].Outcome == "" {
+		return &r.Attempts[n-1]
+	}
+	return nil
+}
+
+// settle closes the running turn. The runner only moves on without a
+// failure line when the turn succeeded.
+func (r *Run) settle(outcome string) {
+	if a := r.open(); a != nil {
+		a.Outcome = outcome
+	}
+}
+
+// Finish records the runner's exit and counts watchdog kills in its logs.
+func (r *Run) Finish(err error, at time.Time) {
+	r.Ended, r.Err = at, err
+	r.ExitCode = ExitCode(err)
+	if r.ExitCode == 0 {
+		r.settle(OK)
+	} else {
+		r.settle(Interrupted)
+	}
+	r.WatchdogKills = CountWatchdogKills(r.LogDirs)
+	if n := len(r.LogDirs); n > 0 && r.exhaustedLast {
+		r.watchdogLast = CountWatchdogKills(r.LogDirs[n-1:]) > 0
+	}
+}
+
+// Outcome sums up how the run ended, once it has.
+func (r Run) Outcome() string {
+	switch {
+	case r.Completed:
+		return OutcomeComplete
+	case r.ExitCode > 128:
+		return OutcomeStopped
+	case r.ExitCode != 0:
+		return OutcomeError
+	case r.watchdogLast:
+		return OutcomeWatchdog
+	case r.exhaustedLast:
+		return OutcomeModelsFailed
+	}
+	return OutcomeMaxIterations
+}
+
+// Duration is how long the runner ran, or has run so far.
+func (r Run) Duration() time.Duration {
+	end := r.Ended
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(r.Started)
+}
+
+// ModelStats counts a model's turns by outcome.
+type ModelStats struct {
+	Model    string
+	Outcomes map[string]int
+}
+
+// Models summarises the attempts per model, in order of first use.
+func (r Run) Models() []ModelStats {
+	var out []ModelStats
+	index := map[string]int{}
+	for _, a := range r.Attempts {
+		i, ok := index[a.Model]
+		if !ok {
+			i = len(out)
+			index[a.Model] = i
+			out = append(out, ModelStats{Model: a.Model, Outcomes: map[string]int{}})
+		}
+		outcome := a.Outcome
+		if outcome == "" {
+			outcome = Interrupted
+		}
+		out[i].Outcomes[outcome]++
+	}
+	return out
+}
+
+// ExitCode maps a process error to a shell-style exit code: 128+n for a
+// signal and 127 when the command couldn't be started.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		if ws, ok := exit.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal())
+		}
+		return exit.ExitCode()
+	}
+	// Exits reported second-hand, e.g. by a supervisor, carry the code.
+	var coded interface{ ExitCode() int }
+	if errors.As(err, &coded) {
+		return coded.ExitCode()
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return 127
+	}
+	return 1
+}
+
+// ExitMeaning explains an exit code in plain words.
+func ExitMeaning(code int) string {
+	switch code {
+	case 0:
+		return "finished cleanly"
+	case 1:
+		return "the runner failed"
+	case 126:
+		return "the runner isn't executable"
+	case 127:
+		return "the runner wasn't found"
+	case 128 + int(syscall.SIGINT):
+		return "interrupted (ctrl+c)"
+	case 128 + int(syscall.SIGKILL):
+		return "killed"
+	case 128 + int(syscall.SIGTERM):
+		return "terminated"
+	}
+	if code > 128 {
+		return "stopped by signal " + syscall.Signal(code-128).String()
+	}
+	return "the runner reported an error"
+}
+
+// watchdogMarkers are written to an iteration's agent log when the
+// runner's watchdog kills the turn.
+var watchdogMarkers = [][]byte{[]byte("[RALPH] TIMEOUT"), []byte("[RALPH] NO OUTPUT")}
+
+// agentLog is the file the runner writes each turn's output to.
+const agentLog = "agent_response.txt"
+
+// CountWatchdogKills counts the iterations whose agent log shows a
+// watchdog kill. The runner only keeps the last turn's log per iteration.
+func CountWatchdogKills(dirs []string) int {
+	n := 0
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, agentLog))
+		if err != nil {
+			continue
+		}
+		for _, m := range watchdogMarkers {
+			if bytes.Contains(data, m) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,119 @@
+package summary
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vibepup-tui/events"
+)
+
+func TestRunRecordsModelOutcomes(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := New([]string{"5"}, start)
+	for _, line := range []string{
+		"🔁 Loop 1 (PLAN Phase)",
+		"   Using: openai/gpt-5.2",
+		"   ⚠️  Model openai/gpt-5.2 failed (Exit: 1). Falling back...",
+		"   Using: google/gemini-3-pro-preview",
+		"🔁 Loop 2 (BUILD Phase)",
+		"   Using: openai/gpt-5.2",
+		"✅ Agent signaled completion.",
+	} {
+		if ev, ok := events.Parse(line); ok {
+			r.Feed(ev)
+		}
+	}
+	r.Finish(nil, start.Add(90*time.Second))
+
+	if r.Iterations != 2 || !r.Completed || r.ExitCode != 0 || r.Duration() != 90*time.Second {
+		t.Fatalf("unexpected run %+v", r)
+	}
+	models := r.Models()
+	if len(models) != 2 || models[0].Model != "openai/gpt-5.2" {
+		t.Fatalf("models = %+v", models)
+	}
+	if got := models[0].Outcomes; got[OK] != 1 || got[Failed] != 1 {
+		t.Errorf("gpt outcomes = %v", got)
+	}
+	if got := models[1].Outcomes; got[OK] != 1 {
+		t.Errorf("gemini outcomes = %v", got)
+	}
+}
+
+func TestFinishWithSignal(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "kill -TERM $$")
+	err := cmd.Run()
+	r := New(nil, time.Now())
+	r.Feed(events.Event{Kind: events.Model, Model: "m"})
+	r.Finish(err, time.Now())
+	if r.ExitCode != 143 || ExitMeaning(r.ExitCode) != "terminated" {
+		t.Errorf("exit %d (%s), want 143", r.ExitCode, ExitMeaning(r.ExitCode))
+	}
+	if r.Attempts[0].Outcome != Interrupted {
+		t.Errorf("running turn should be interrupted, got %q", r.Attempts[0].Outcome)
+	}
+}
+
+func TestCountWatchdogKills(t *testing.T) {
+	root := t.TempDir()
+	logs := map[string]string{
+		"iter-0001": "did some work\n",
+		"iter-0002": "thinking\n[RALPH] TIMEOUT: killing opencode turn\n",
+		"iter-0003": "[RALPH] NO OUTPUT: likely waiting for input / hung tool\n[RALPH] NO OUTPUT: likely waiting for input / hung tool\n",
+	}
+	var dirs []string
+	for name, body := range logs {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, agentLog), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, dir)
+	}
+	dirs = append(dirs, filepath.Join(root, "missing"))
+	if n := CountWatchdogKills(dirs); n != 2 {
+		t.Errorf("CountWatchdogKills = %d, want 2", n)
+	}
+}
+
+func TestRunOutcome(t *testing.T) {
+	root := t.TempDir()
+	killed := filepath.Join(root, "iter-0002")
+	if err := os.Mkdir(killed, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(killed, agentLog), []byte("[RALPH] TIMEOUT: killing opencode turn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	failed := exec.Command("sh", "-c", "exit 1").Run()
+	cases := []struct {
+		name  string
+		lines []string
+		err   error
+		want  string
+	}{
+		{"complete", []string{"🔁 Loop 1 (BUILD Phase)", "✅ Agent signaled completion."}, nil, OutcomeComplete},
+		{"out of loops", []string{"🔁 Loop 1 (BUILD Phase)", "🔁 Loop 2 (BUILD Phase)"}, nil, OutcomeMaxIterations},
+		{"recovered", []string{"🔁 Loop 1 (BUILD Phase)", "❌ All models failed this iteration.", "🔁 Loop 2 (BUILD Phase)"}, nil, OutcomeMaxIterations},
+		{"models failed", []string{"🔁 Loop 1 (BUILD Phase)", "   Logs: " + filepath.Join(root, "iter-0001"), "❌ All models failed this iteration."}, nil, OutcomeModelsFailed},
+		{"watchdog", []string{"🔁 Loop 2 (BUILD Phase)", "   Logs: " + killed, "❌ All models failed this iteration."}, nil, OutcomeWatchdog},
+		{"error", []string{"🔁 Loop 1 (BUILD Phase)"}, failed, OutcomeError},
+	}
+	for _, tc := range cases {
+		r := New(nil, time.Now())
+		for _, line := range tc.lines {
+			if ev, ok := events.Parse(line); ok {
+				r.Feed(ev)
+			}
+		}
+		r.Finish(tc.err, time.Now())
+		if got := r.Outcome(); got != tc.want {
+			t.Errorf("%s: Outcome = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package tasks
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File is the checklist the runner works through.
+const File = "prd.md"
+
+// Task is one checkbox item in the PRD.
+type Task struct {
+	Text string
+	Done bool
+	Line int // 1-based line in the file
+}
+
+// List is the parsed checklist.
+type List struct {
+	Tasks []Task
+}
+
+var checkbox = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.*)$`)
+
+// Parse reads markdown checkbox items ("- [ ] task", "- [x] task").
+func Parse(r io.Reader) List {
+	var l List
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		m := checkbox.FindStringSubmatch(sc.Text())
+		if m == nil {
+			continue
+		}
+		l.Tasks = append(l.Tasks, Task{Text: strings.TrimSpace(m[2]), Done: m[1] != " ", Line: n})
+	}
+	return l
+}
+
+// Load parses the PRD in dir. A missing file is an empty list.
+func Load(dir string) (List, error) {
+	f, err := os.Open(filepath.Join(dir, File))
+	if os.IsNotExist(err) {
+		return List{}, nil
+	}
+	if err != nil {
+		return List{}, err
+	}
+	defer f.Close()
+	return Parse(f), nil
+}
+
+// Append adds an unchecked task to the end of the PRD in dir, creating
+// the file if there isn't one.
+func Append(dir, text string) error {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return errors.New("the task is empty")
+	}
+	path := filepath.Join(dir, File)
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	item := "- [ ] " + text + "\n"
+	if len(old) > 0 && !bytes.HasSuffix(old, []byte("\n")) {
+		item = "\n" + item
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(item); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Done returns the number of checked tasks.
+func (l List) Done() int {
+	n := 0
+	for _, t := range l.Tasks {
+		if t.Done {
+			n++
+		}
+	}
+	return n
+}
+
+// Total returns the number of tasks.
+func (l List) Total() int {
+	return len(l.Tasks)
+}
+
+// Next returns the first unchecked task, the one the runner picks up.
+func (l List) Next() (Task, bool) {
+	for _, t := range l.Tasks {
+		if !t.Done {
+			return t, true
+		}
+	}
+	return Task{}, false
+}
+
+// CompletedSince returns the tasks done now that weren't done in before,
+// matched by text since lines move as the PRD is edited.
+func (l List) CompletedSince(before List) []Task {
+	was := map[string]bool{}
+	for _, t := range before.Tasks {
+		was[t.Text] = t.Done
+	}
+	var out []Task
+	for _, t := range l.Tasks {
+		if t.Done && !was[t.Text] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseChecklist(t *testing.T) {
+	l := Parse(strings.NewReader(`# PRD
+
+Some prose with [brackets] that isn't a task.
+
+- [x] Set up the project
+- [ ] Add the login page
+  * [X] Nested and done
++ [ ]   Write docs
+`))
+	if l.Total() != 4 || l.Done() != 2 {
+		t.Fatalf("got %d tasks, %d done: %+v", l.Total(), l.Done(), l.Tasks)
+	}
+	next, ok := l.Next()
+	if !ok || next.Text != "Add the login page" || next.Line != 6 {
+		t.Errorf("Next = %+v, %v", next, ok)
+	}
+	if l.Tasks[3].Text != "Write docs" {
+		t.Errorf("text should be trimmed, got %q", l.Tasks[3].Text)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	l, err := Load(t.TempDir())
+	if err != nil || l.Total() != 0 {
+		t.Errorf("missing prd.md should be an empty list, got %+v, %v", l, err)
+	}
+	if _, ok := l.Next(); ok {
+		t.Error("empty list has no next task")
+	}
+}
+
+func TestCompletedSince(t *testing.T) {
+	before := Parse(strings.NewReader("- [x] one\n- [ ] two\n- [ ] three\n"))
+	after := Parse(strings.NewReader("- [ ] zero\n- [x] one\n- [x] two\n- [ ] three\n"))
+	done := after.CompletedSince(before)
+	if len(done) != 1 || done[0].Text != "two" {
+		t.Errorf("CompletedSince = %+v, want just two", done)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, File), []byte("# PRD\n- [x] one"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Append(dir, "  two,\n  on one line "); err != nil {
+		t.Fatal(err)
+	}
+	if err := Append(dir, " "); err == nil {
+		t.Error("an empty task should be refused")
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, File))
+	if want := "# PRD\n- [x] one\n- [ ] two, on one line\n"; string(got) != want {
+		t.Errorf("prd.md = %q, want %q", got, want)
+	}
+
+	fresh := t.TempDir()
+	if err := Append(fresh, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if l, _ := Load(fresh); l.Total() != 1 || l.Tasks[0].Text != "first" {
+		t.Errorf("Append should create prd.md, got %+v", l.Tasks)
+	}
+}
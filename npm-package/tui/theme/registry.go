@@ -1,6 +1,10 @@
 package theme
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 type Theme struct {
 	Name          string
@@ -65,6 +69,22 @@ func Get(name string) Theme {
 	return themes["dracula-vibe"]
 }
 
+// Lookup returns the named theme and whether it is registered.
+func Lookup(name string) (Theme, bool) {
+	t, ok := themes[name]
+	return t, ok
+}
+
+// Names returns the registered theme names in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func All() []Theme {
 	items := make([]Theme, 0, len(themes))
 	for _, t := range themes {
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"vibepup-tui/activity"
+	"vibepup-tui/theme"
+)
+
+// ActivityPanel renders the agent's recent tool calls with per-iteration
+// counts.
+type ActivityPanel struct {
+	Theme theme.Theme
+	// Offset skips that many of the newest calls, to scroll back.
+	Offset int
+}
+
+// Render draws at most height lines, newest call first. The running call's
+// duration keeps counting up until the next one starts.
+func (p ActivityPanel) Render(feed *activity.Feed, width, height int, now time.Time) string {
+	if feed == nil || height <= 0 {
+		return ""
+	}
+	title := lipgloss.NewStyle().Foreground(p.Theme.Accent).Bold(true)
+	muted := lipgloss.NewStyle().Foreground(p.Theme.Muted)
+	tool := lipgloss.NewStyle().Foreground(p.Theme.Highlight)
+	text := lipgloss.NewStyle().Foreground(p.Theme.Foreground)
+
+	counts := make([]string, 0, len(feed.Counts()))
+	for _, c := range feed.Counts() {
+		counts = append(counts, fmt.Sprintf("%s×%d", c.Tool, c.N))
+	}
+	head := fmt.Sprintf("ACTIVITY loop %d · %s", feed.Iteration(), feed.Mode())
+	if len(counts) > 0 {
+		head += " · " + strings.Join(counts, " ")
+	}
+	lines := []string{title.Render(ClampWidth(head, width))}
+
+	recent := feed.Recent(height - 1 + p.Offset)
+	for _, c := range recent[min(max(p.Offset, 0), len(recent)):] {
+		when := c.Start.Format("15:04:05")
+		took := FormatDuration(c.Duration(now))
+		if c.End.IsZero() {
+			took = "…" + took
+		}
+		prefix := fmt.Sprintf("%s %7s ", when, took)
+		name := fmt.Sprintf("%-6s ", c.Tool)
+		rest := ClampWidth(c.Title, max(width-Width(prefix)-Width(name), 0))
+		lines = append(lines, ClampWidth(muted.Render(prefix)+tool.Render(name)+text.Render(rest), width))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatDuration renders d compactly: 850ms, 12s, 3m05s, 1h02m.
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
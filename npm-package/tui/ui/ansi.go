@@ -0,0 +1,275 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"vibepup-tui/theme"
+)
+
+const tabWidth = 8
+
+// Sanitize makes runner output safe to place in a fixed-size view. SGR
+// styling is kept; cursor movement, screen and title sequences, OSC
+// payloads and stray control characters are dropped. A carriage return
+// restarts the line, as a terminal would overwrite it, and tabs expand to
+// spaces so widths stay predictable.
+func Sanitize(s string) string {
+	var b, sgr strings.Builder
+	p := ansi.NewParser()
+	var state byte
+	col := 0
+	for len(s) > 0 {
+		seq, width, n, newState := ansi.DecodeSequence(s, state, p)
+		state = newState
+		s = s[n:]
+		switch {
+		case width > 0:
+			b.WriteString(seq)
+			col += width
+		case isSGR(seq):
+			b.WriteString(seq)
+			sgr.WriteString(seq)
+		case seq == "\t":
+			pad := tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", pad))
+			col += pad
+		case seq == "\r":
+			if s == "" || s == "\n" {
+				continue // CRLF
+			}
+			// Keep the styling in effect; the text gets overwritten.
+			b.Reset()
+			b.WriteString(sgr.String())
+			col = 0
+		}
+	}
+	return b.String()
+}
+
+// isSGR reports whether seq is a Select Graphic Rendition sequence, the only
+// CSI sequence that styles text rather than moving the cursor.
+func isSGR(seq string) bool {
+	if !strings.HasPrefix(seq, "\x1b[") || !strings.HasSuffix(seq, "m") {
+		return false
+	}
+	return !strings.ContainsAny(seq[2:len(seq)-1], "<=>?$ ")
+}
+
+// Span is a run of text sharing one style.
+type Span struct {
+	Text  string
+	Style lipgloss.Style
+}
+
+// Palette maps the 16 basic ANSI colours (0-7 and their bright variants
+// 8-15) to other colours. Extended and true colours are left alone.
+type Palette [16]lipgloss.TerminalColor
+
+// ThemePalette maps the basic colours onto a theme so tool output matches
+// the rest of the TUI: errors take the accent, success the highlight.
+func ThemePalette(t theme.Theme) *Palette {
+	black, red, green, yellow := t.Muted, t.Accent, t.Highlight, t.Highlight
+	blue, magenta, cyan, white := t.AccentAlt, t.Border, t.AccentAlt, t.Foreground
+	return &Palette{
+		black, red, green, yellow, blue, magenta, cyan, white,
+		black, red, green, yellow, blue, magenta, cyan, white,
+	}
+}
+
+// sgrState is the text style built up by a sequence of SGR codes.
+type sgrState struct {
+	fg, bg                     lipgloss.TerminalColor
+	bold, faint, italic, under bool
+	blink, reverse, strike     bool
+}
+
+func (st *sgrState) apply(params ansi.Params) {
+	if len(params) == 0 {
+		*st = sgrState{}
+		return
+	}
+	for i := 0; i < len(params); i++ {
+		switch code := params[i].Param(0); {
+		case code == 0:
+			*st = sgrState{}
+		case code == 1:
+			st.bold = true
+		case code == 2:
+			st.faint = true
+		case code == 3:
+			st.italic = true
+		case code == 4:
+			st.under = true
+		case code == 5:
+			st.blink = true
+		case code == 7:
+			st.reverse = true
+		case code == 9:
+			st.strike = true
+		case code == 22:
+			st.bold, st.faint = false, false
+		case code == 23:
+			st.italic = false
+		case code == 24:
+			st.under = false
+		case code == 25:
+			st.blink = false
+		case code == 27:
+			st.reverse = false
+		case code == 29:
+			st.strike = false
+		case code >= 30 && code <= 37:
+			st.fg = lipgloss.ANSIColor(code - 30)
+		case code >= 90 && code <= 97:
+			st.fg = lipgloss.ANSIColor(code - 90 + 8)
+		case code >= 40 && code <= 47:
+			st.bg = lipgloss.ANSIColor(code - 40)
+		case code >= 100 && code <= 107:
+			st.bg = lipgloss.ANSIColor(code - 100 + 8)
+		case code == 39:
+			st.fg = nil
+		case code == 49:
+			st.bg = nil
+		case code == 38 || code == 48:
+			c, used := extendedColor(params[i+1:])
+			i += used
+			if code == 38 {
+				st.fg = c
+			} else {
+				st.bg = c
+			}
+		}
+	}
+}
+
+// extendedColor reads the 5;n or 2;r;g;b tail of a 38/48 code and returns
+// the colour and the number of parameters consumed.
+func extendedColor(params ansi.Params) (lipgloss.TerminalColor, int) {
+	if len(params) == 0 {
+		return nil, 0
+	}
+	switch params[0].Param(0) {
+	case 5:
+		if len(params) >= 2 {
+			return lipgloss.ANSIColor(params[1].Param(0)), 2
+		}
+	case 2:
+		if len(params) >= 4 {
+			r, g, b := params[1].Param(0), params[2].Param(0), params[3].Param(0)
+			return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", r, g, b)), 4
+		}
+	}
+	return nil, len(params)
+}
+
+func (st sgrState) style(pal *Palette) lipgloss.Style {
+	s := lipgloss.NewStyle().
+		Bold(st.bold).Faint(st.faint).Italic(st.italic).Underline(st.under).
+		Blink(st.blink).Reverse(st.reverse).Strikethrough(st.strike)
+	if st.fg != nil {
+		s = s.Foreground(pal.remap(st.fg))
+	}
+	if st.bg != nil {
+		s = s.Background(pal.remap(st.bg))
+	}
+	return s
+}
+
+func (p *Palette) remap(c lipgloss.TerminalColor) lipgloss.TerminalColor {
+	if n, ok := c.(lipgloss.ANSIColor); ok && p != nil && n < 16 && p[n] != nil {
+		return p[n]
+	}
+	return c
+}
+
+// ParseANSI splits s into styled spans, translating SGR codes into lipgloss
+// styles with basic colours remapped through pal (nil keeps them). Other
+// escape sequences are dropped; run Sanitize first to handle \r and tabs.
+func ParseANSI(s string, pal *Palette) []Span {
+	var spans []Span
+	var st sgrState
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			spans = append(spans, Span{Text: text.String(), Style: st.style(pal)})
+			text.Reset()
+		}
+	}
+	p := ansi.NewParser()
+	var state byte
+	for len(s) > 0 {
+		seq, width, n, newState := ansi.DecodeSequence(s, state, p)
+		state = newState
+		s = s[n:]
+		switch {
+		case width > 0:
+			text.WriteString(seq)
+		case isSGR(seq):
+			flush()
+			st.apply(p.Params())
+		}
+	}
+	flush()
+	return spans
+}
+
+// Restyle re-renders ANSI-styled text through lipgloss with basic colours
+// remapped through pal.
+func Restyle(s string, pal *Palette) string {
+	if !strings.Contains(s, "\x1b[") {
+		return s
+	}
+	var b strings.Builder
+	for _, sp := range ParseANSI(s, pal) {
+		b.WriteString(sp.Style.Render(sp.Text))
+	}
+	return b.String()
+}
+
+// Width returns the number of terminal cells s occupies, ignoring escape
+// sequences and counting wide characters as two.
+func Width(s string) int {
+	return ansi.StringWidth(s)
+}
+
+// Wrap breaks s into lines of at most width cells, preferring word
+// boundaries. Styling that is open at the end of a line is reopened at the
+// start of the next so each line renders correctly on its own.
+func Wrap(s string, width int) []string {
+	if width <= 0 {
+		return []string{""}
+	}
+	lines := strings.Split(ansi.Wrap(s, width, ""), "\n")
+	var open string
+	for i, line := range lines {
+		lines[i] = open + line
+		open = openSGR(lines[i])
+		if open != "" {
+			lines[i] += ansi.ResetStyle
+		}
+	}
+	return lines
+}
+
+// openSGR returns the SGR sequences still in effect at the end of s.
+func openSGR(s string) string {
+	var open strings.Builder
+	var state byte
+	for len(s) > 0 {
+		seq, _, n, newState := ansi.DecodeSequence(s, state, nil)
+		state = newState
+		s = s[n:]
+		if !isSGR(seq) {
+			continue
+		}
+		if seq == "\x1b[m" || seq == "\x1b[0m" {
+			open.Reset()
+			continue
+		}
+		open.WriteString(seq)
+	}
+	return open.String()
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"vibepup-tui/theme"
+)
+
+func TestSanitize(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"keeps sgr", "\x1b[91m\x1b[1m| \x1b[0m\x1b[90m Bash", "\x1b[91m\x1b[1m| \x1b[0m\x1b[90m Bash"},
+		{"drops cursor movement", "a\x1b[2Kb\x1b[1A\x1b[10;5Hc\x1b[?25l", "abc"},
+		{"drops osc", "\x1b]0;title\x07done", "done"},
+		{"carriage return overwrites", "\x1b[32m50%\r100%", "\x1b[32m100%"},
+		{"crlf", "line\r", "line"},
+		{"tabs expand", "ab\tc", "ab      c"},
+		{"controls dropped", "be\bep\x07", "beep"},
+	}
+	for _, tc := range cases {
+		if got := Sanitize(tc.in); got != tc.want {
+			t.Errorf("%s: Sanitize(%q) = %q, want %q", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestClampWidthCountsCells(t *testing.T) {
+	cases := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"hello", 3, "hel"},
+		{"日本語テキスト", 5, "日本"},
+		{"🐶🐶🐶", 4, "🐶🐶"},
+		{"\x1b[31mred text\x1b[0m", 3, "\x1b[31mred\x1b[0m"},
+	}
+	for _, tc := range cases {
+		got := ClampWidth(tc.in, tc.width)
+		if ansi.Strip(got) != ansi.Strip(tc.want) || Width(got) > tc.width {
+			t.Errorf("ClampWidth(%q, %d) = %q (width %d), want %q", tc.in, tc.width, got, Width(got), tc.want)
+		}
+	}
+}
+
+func TestWrapReopensStyles(t *testing.T) {
+	lines := Wrap("\x1b[32mgreen words keep going\x1b[0m", 10)
+	if len(lines) < 2 {
+		t.Fatalf("expected wrapping, got %q", lines)
+	}
+	for _, l := range lines {
+		if Width(l) > 10 {
+			t.Errorf("line %q is %d cells wide", l, Width(l))
+		}
+	}
+	if got := lines[1]; got[:5] != "\x1b[32m" {
+		t.Errorf("second line should reopen the colour, got %q", got)
+	}
+}
+
+func TestParseANSI(t *testing.T) {
+	spans := ParseANSI("plain \x1b[1;31merror\x1b[0m \x1b[38;2;1;2;3mrgb", ThemePalette(themeForTest))
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans: %+v", len(spans), spans)
+	}
+	if !spans[1].Style.GetBold() || spans[1].Style.GetForeground() != themeForTest.Accent {
+		t.Errorf("bold red should map to the theme accent, got %+v", spans[1].Style)
+	}
+	if spans[2].Style.GetBold() {
+		t.Errorf("reset should clear bold")
+	}
+	if fg := spans[3].Style.GetForeground(); fg != lipgloss.Color("#010203") {
+		t.Errorf("true colour should pass through, got %v", fg)
+	}
+}
+
+var themeForTest = theme.Get("dracula-vibe")
@@ -0,0 +1,203 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// block is a tool call in the log: a header line followed by the tool's
+// output, up to the next header, a runner line or the agent's prose after a
+// run of blank lines.
+type block struct {
+	header    int // line index of the header
+	end       int // index after the last output line, -1 while still open
+	collapsed bool
+}
+
+// WriteToolHeader starts a collapsible block with line as its header. The
+// block takes every following line until the next header, EndBlock or a
+// line after proseGap blank ones.
+func (l *LogViewport) WriteToolHeader(line string) {
+	l.EndBlock()
+	l.blocks = append(l.blocks, block{header: l.store.Total(), end: -1, collapsed: !l.expandAll})
+	l.WriteLine(line)
+}
+
+// EndBlock closes the open block, if any, so later lines stay visible.
+func (l *LogViewport) EndBlock() {
+	if n := len(l.blocks); n > 0 && l.blocks[n-1].end < 0 {
+		l.blocks[n-1].end = l.store.Total()
+	}
+	l.blankRun = 0
+}
+
+// proseGap is how many blank lines in a row end a tool's output. Single
+// ones are part of it, as between the directories of ls -R; opencode puts
+// more before the agent talks again.
+const proseGap = 2
+
+// endBlockAtProse closes the open block before a run of blank lines once
+// a line that isn't blank follows it.
+func (l *LogViewport) endBlockAtProse(blank bool) {
+	n := len(l.blocks)
+	if n == 0 || l.blocks[n-1].end >= 0 {
+		return
+	}
+	if blank {
+		l.blankRun++
+		return
+	}
+	if l.blankRun >= proseGap {
+		l.blocks[n-1].end = l.store.Total() - l.blankRun
+	}
+	l.blankRun = 0
+}
+
+// WriteNote writes a line of the TUI's own, outside any tool block.
+func (l *LogViewport) WriteNote(line string) {
+	l.EndBlock()
+	l.WriteLine(line)
+}
+
+func (b block) last(total int) int {
+	if b.end < 0 {
+		return total
+	}
+	return b.end
+}
+
+// blockAt returns the index of the block whose body or header holds line i.
+func (l *LogViewport) blockAt(i int) (int, bool) {
+	k := sort.Search(len(l.blocks), func(k int) bool { return l.blocks[k].header > i }) - 1
+	if k < 0 || i >= l.blocks[k].last(l.store.Total()) {
+		return 0, false
+	}
+	return k, true
+}
+
+// hidden reports whether line i is folded away inside a collapsed block.
+func (l *LogViewport) hidden(i int) bool {
+	k, ok := l.blockAt(i)
+	return ok && l.blocks[k].collapsed && i != l.blocks[k].header
+}
+
+// nextVisible returns the first visible line after i.
+func (l *LogViewport) nextVisible(i int) int {
+	i++
+	if l.hidden(i) {
+		k, _ := l.blockAt(i)
+		i = l.blocks[k].last(l.store.Total())
+	}
+	return i
+}
+
+// prevVisible returns the last visible line before i.
+func (l *LogViewport) prevVisible(i int) int {
+	i--
+	if l.hidden(i) {
+		k, _ := l.blockAt(i)
+		i = l.blocks[k].header
+	}
+	return i
+}
+
+// lastVisible returns the newest visible line, or First if the log is empty.
+func (l *LogViewport) lastVisible() int {
+	if l.store.Total() == 0 {
+		return l.store.First()
+	}
+	return l.prevVisible(l.store.Total())
+}
+
+// ToggleBlock expands or collapses the last tool block whose header is on
+// screen, or else the block the top line belongs to. It returns false if
+// there is no block in view.
+func (l *LogViewport) ToggleBlock() bool {
+	from, to := l.visible()
+	target, found := 0, false
+	for i := from; i < to; i = l.nextVisible(i) {
+		if k, ok := l.blockAt(i); ok && l.blocks[k].header == i {
+			target, found = k, true
+		}
+	}
+	if !found {
+		if target, found = l.blockAt(from); !found {
+			return false
+		}
+	}
+	b := &l.blocks[target]
+	b.collapsed = !b.collapsed
+	if b.header < l.yOffset {
+		l.SetYOffset(b.header)
+	} else {
+		l.SetYOffset(l.yOffset)
+	}
+	return true
+}
+
+// CollapseAll collapses every tool block, or expands them all when
+// collapse is false. New blocks follow the same setting.
+func (l *LogViewport) CollapseAll(collapse bool) {
+	l.expandAll = !collapse
+	for k := range l.blocks {
+		l.blocks[k].collapsed = collapse
+	}
+	if k, ok := l.blockAt(l.yOffset); ok {
+		l.yOffset = l.blocks[k].header
+	}
+	if l.following() {
+		l.GotoBottom()
+	} else {
+		l.SetYOffset(l.yOffset)
+	}
+}
+
+// AllCollapsed reports whether new tool blocks start collapsed.
+func (l *LogViewport) AllCollapsed() bool {
+	return !l.expandAll
+}
+
+// unfold expands the block hiding line i, if any.
+func (l *LogViewport) unfold(i int) {
+	if l.hidden(i) {
+		k, _ := l.blockAt(i)
+		l.blocks[k].collapsed = false
+	}
+}
+
+// pruneBlocks forgets blocks that have left the scrollback entirely.
+func (l *LogViewport) pruneBlocks() {
+	first := l.store.First()
+	n := 0
+	for n < len(l.blocks) && l.blocks[n].end >= 0 && l.blocks[n].end <= first {
+		n++
+	}
+	if n > 0 {
+		l.blocks = append(l.blocks[:0], l.blocks[n:]...)
+	}
+}
+
+// foldMarker decorates a block header with its fold state and, when
+// collapsed, the number of hidden lines.
+func (l *LogViewport) foldMarker(i int, line string) string {
+	k, ok := l.blockAt(i)
+	if !ok || l.blocks[k].header != i {
+		return line
+	}
+	b := l.blocks[k]
+	if !b.collapsed {
+		return "▾ " + line
+	}
+	n := b.last(l.store.Total()) - b.header - 1
+	if n == 0 {
+		return "▸ " + line
+	}
+	noun := "lines"
+	if n == 1 {
+		noun = "line"
+	}
+	summary := lipgloss.NewStyle().Foreground(l.Theme.Muted).Render(fmt.Sprintf("  … %d %s", n, noun))
+	return "▸ " + line + summary
+}
@@ -0,0 +1,30 @@
+package ui
+
+import "strings"
+
+// StripEmoji removes pictographic emoji (and the variation selectors and
+// joiners that glue them together) for terminals that render them badly.
+func StripEmoji(s string) string {
+	return strings.Map(func(r rune) rune {
+		if isEmoji(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, transport, flags
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // misc symbols and dingbats
+		return true
+	case r >= 0x23E9 && r <= 0x23FA: // media controls (⏸ ⏩ ...)
+		return true
+	case r == 0x200D, r == 0xFE0F, r == 0x20E3: // ZWJ, emoji presentation, keycap
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows and stars used as emoji
+		return true
+	}
+	return false
+}
@@ -1,17 +1,317 @@
 package ui
 
 import (
-	"unicode/utf8"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
-// ClampWidth trims a string to fit within width runes.
+// ClampWidth trims a string to fit within width terminal cells. Escape
+// sequences take no space and wide characters count as two cells, so a
+// character that would straddle the edge is dropped.
 func ClampWidth(s string, width int) string {
 	if width <= 0 {
 		return ""
 	}
-	if utf8.RuneCountInString(s) <= width {
-		return s
+	return ansi.Truncate(s, width, "")
+}
+
+// Pane is a region of the running screen.
+type Pane string
+
+const (
+	PaneLog      Pane = "log"
+	PaneTasks    Pane = "tasks"
+	PaneStatus   Pane = "status"
+	PaneDog      Pane = "dog"
+	PaneActivity Pane = "activity"
+)
+
+// Panes lists every pane name.
+func Panes() []Pane {
+	return []Pane{PaneLog, PaneTasks, PaneStatus, PaneDog, PaneActivity}
+}
+
+// ParsePanes reads a comma-separated pane list such as "status,tasks".
+func ParsePanes(s string) ([]Pane, error) {
+	var out []Pane
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p := Pane(name)
+		if p == PaneLog {
+			return nil, fmt.Errorf("the log always takes the main area")
+		}
+		known := false
+		for _, q := range Panes() {
+			known = known || q == p
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown pane %q", name)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Arrangement says which panes are shown around the log and how big they
+// are. The log fills the top-left; Side panes stack in a column to its right
+// and Bottom panes sit side by side underneath.
+type Arrangement struct {
+	Name   string
+	Side   []Pane
+	Bottom []Pane
+	// SideWidth is the side column's share of the width, in percent.
+	SideWidth int
+	// BottomHeight is the bottom row's height in rows, borders included.
+	BottomHeight int
+	// Header shows the animated header above the panes.
+	Header bool
+}
+
+// Split size limits, so a resize can't squeeze the log away.
+const (
+	minSideWidth    = 15
+	maxSideWidth    = 60
+	minBottomHeight = 3
+	maxBottomHeight = 20
+)
+
+// Presets returns the built-in arrangements in the order the preset key
+// cycles through them.
+func Presets() []Arrangement {
+	return []Arrangement{
+		{Name: "focus-log", Bottom: []Pane{PaneActivity}, BottomHeight: 8, Header: true},
+		{Name: "dashboard", Side: []Pane{PaneStatus, PaneDog, PaneTasks, PaneActivity}, SideWidth: 38, Header: true},
+		{Name: "zen"},
+	}
+}
+
+// PresetNames returns the preset names in cycling order.
+func PresetNames() []string {
+	var names []string
+	for _, a := range Presets() {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+// Preset returns the named arrangement.
+func Preset(name string) (Arrangement, bool) {
+	for _, a := range Presets() {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Arrangement{}, false
+}
+
+// NextPreset returns the preset after the named one, wrapping around.
+func NextPreset(name string) Arrangement {
+	p := Presets()
+	for i, a := range p {
+		if a.Name == name {
+			return p[(i+1)%len(p)]
+		}
+	}
+	return p[0]
+}
+
+// Visible lists the shown panes in focus order, log first.
+func (a Arrangement) Visible() []Pane {
+	return append(append([]Pane{PaneLog}, a.Side...), a.Bottom...)
+}
+
+// Has reports whether p is part of the arrangement.
+func (a Arrangement) Has(p Pane) bool {
+	for _, q := range a.Visible() {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Without returns the arrangement with p removed.
+func (a Arrangement) Without(p Pane) Arrangement {
+	drop := func(ps []Pane) []Pane {
+		var out []Pane
+		for _, q := range ps {
+			if q != p {
+				out = append(out, q)
+			}
+		}
+		return out
+	}
+	a.Side, a.Bottom = drop(a.Side), drop(a.Bottom)
+	return a
+}
+
+// ResizeSide grows the side column by delta percent, within limits.
+func (a *Arrangement) ResizeSide(delta int) {
+	a.SideWidth = min(max(a.SideWidth+delta, minSideWidth), maxSideWidth)
+}
+
+// ResizeBottom grows the bottom row by delta rows, within limits.
+func (a *Arrangement) ResizeBottom(delta int) {
+	a.BottomHeight = min(max(a.BottomHeight+delta, minBottomHeight), maxBottomHeight)
+}
+
+// Layout is the measured screen: the header and footer are rendered first
+// and the panes share what is left.
+type Layout struct {
+	HeaderHeight int
+	FooterHeight int
+	Width        int
+	Height       int
+}
+
+// Measure builds a layout around an already rendered header and footer.
+func Measure(width, height int, header, footer string) Layout {
+	l := Layout{Width: width, Height: height}
+	if header != "" {
+		l.HeaderHeight = lipgloss.Height(header)
+	}
+	if footer != "" {
+		l.FooterHeight = lipgloss.Height(footer)
+	}
+	return l
+}
+
+// Body returns the size of the area between header and footer.
+func (l Layout) Body() (int, int) {
+	return l.Width, max(l.Height-l.HeaderHeight-l.FooterHeight, 0)
+}
+
+// Rect is the size of a pane, borders included.
+type Rect struct {
+	Width, Height int
+}
+
+// fixedHeight is the height a side pane needs regardless of the screen.
+var fixedHeight = map[Pane]int{PaneDog: 5}
+
+// Split sizes every visible pane of a within the body.
+func (l Layout) Split(a Arrangement) map[Pane]Rect {
+	w, h := l.Body()
+	rects := map[Pane]Rect{}
+
+	bottomH := 0
+	if len(a.Bottom) > 0 {
+		bottomH = min(a.BottomHeight, h/2)
+		for i, p := range a.Bottom {
+			rects[p] = Rect{share(w, len(a.Bottom), i), bottomH}
+		}
+	}
+	topH := h - bottomH
+
+	sideW := 0
+	if len(a.Side) > 0 {
+		sideW = w * a.SideWidth / 100
+		flex, rest := 0, topH
+		for _, p := range a.Side {
+			if n, ok := fixedHeight[p]; ok {
+				rest -= n
+			} else {
+				flex++
+			}
+		}
+		i := 0
+		for _, p := range a.Side {
+			if n, ok := fixedHeight[p]; ok {
+				rects[p] = Rect{sideW, n}
+				continue
+			}
+			rects[p] = Rect{sideW, share(max(rest, 0), flex, i)}
+			i++
+		}
+	}
+	rects[PaneLog] = Rect{w - sideW, topH}
+	return rects
+}
+
+// share splits total into n parts and returns part i; earlier parts take
+// the remainder.
+func share(total, n, i int) int {
+	s := total / n
+	if i < total%n {
+		s++
+	}
+	return s
+}
+
+// Compose renders the body: render is called for each visible pane with
+// its size and must return content of exactly that size.
+func (l Layout) Compose(a Arrangement, render func(Pane, Rect) string) string {
+	rects := l.Split(a)
+	top := render(PaneLog, rects[PaneLog])
+	if len(a.Side) > 0 {
+		side := make([]string, 0, len(a.Side))
+		for _, p := range a.Side {
+			if rects[p].Height > 0 {
+				side = append(side, render(p, rects[p]))
+			}
+		}
+		top = lipgloss.JoinHorizontal(lipgloss.Top, top, lipgloss.JoinVertical(lipgloss.Left, side...))
+	}
+	if len(a.Bottom) == 0 {
+		return top
+	}
+	bottom := make([]string, 0, len(a.Bottom))
+	for _, p := range a.Bottom {
+		bottom = append(bottom, render(p, rects[p]))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, top, lipgloss.JoinHorizontal(lipgloss.Top, bottom...))
+}
+
+// Fit clips or pads s to exactly width cells by height rows.
+func Fit(s string, width, height int) string {
+	if height <= 0 {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	for i, line := range lines {
+		line = ClampWidth(line, width)
+		lines[i] = line + strings.Repeat(" ", max(width-Width(line), 0))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Frame draws a rounded border with title around content, fitting it to
+// r. The border takes the accent colour when the pane has focus.
+func Frame(title, content string, r Rect, focused bool, border, accent lipgloss.TerminalColor) string {
+	if r.Width < 4 || r.Height < 2 {
+		return Fit("", r.Width, r.Height)
+	}
+	color := border
+	if focused {
+		color = accent
+	}
+	style := lipgloss.NewStyle().Foreground(color)
+	inner := r.Width - 2
+
+	label := ClampWidth(" "+title+" ", inner-1)
+	fill := strings.Repeat("─", max(inner-1-Width(label), 0))
+	labelStyle := style
+	if focused {
+		labelStyle = labelStyle.Bold(true)
+	}
+	out := []string{style.Render("╭─") + labelStyle.Render(label) + style.Render(fill+"╮")}
+	if r.Height > 2 {
+		for _, row := range strings.Split(Fit(content, inner, r.Height-2), "\n") {
+			out = append(out, style.Render("│")+row+style.Render("│"))
+		}
 	}
-	runes := []rune(s)
-	return string(runes[:width])
+	out = append(out, style.Render("╰"+strings.Repeat("─", inner)+"╯"))
+	return strings.Join(out, "\n")
 }
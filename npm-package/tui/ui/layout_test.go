@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitFillsTheBody(t *testing.T) {
+	l := Measure(100, 40, "one\ntwo\nthree", "help")
+	if _, h := l.Body(); h != 36 {
+		t.Fatalf("body height = %d, want 36", h)
+	}
+
+	dash, _ := Preset("dashboard")
+	r := l.Split(dash)
+	if r[PaneLog].Width+r[PaneStatus].Width != 100 || r[PaneLog].Height != 36 {
+		t.Errorf("log %+v and side %+v should fill the body", r[PaneLog], r[PaneStatus])
+	}
+	side := 0
+	for _, p := range dash.Side {
+		side += r[p].Height
+	}
+	if side != 36 || r[PaneDog].Height != fixedHeight[PaneDog] {
+		t.Errorf("side panes take %d rows, dog %d", side, r[PaneDog].Height)
+	}
+
+	focus, _ := Preset("focus-log")
+	r = l.Split(focus)
+	if r[PaneLog].Height+r[PaneActivity].Height != 36 || r[PaneLog].Width != 100 {
+		t.Errorf("log %+v and bottom %+v should fill the body", r[PaneLog], r[PaneActivity])
+	}
+}
+
+func TestComposeMatchesScreenSize(t *testing.T) {
+	l := Measure(80, 24, "", "")
+	for _, a := range Presets() {
+		out := l.Compose(a, func(p Pane, r Rect) string {
+			return Frame(string(p), "x", r, p == PaneLog, nil, nil)
+		})
+		rows := strings.Split(out, "\n")
+		if len(rows) != 24 {
+			t.Errorf("%s: %d rows, want 24", a.Name, len(rows))
+		}
+		for _, row := range rows {
+			if Width(row) != 80 {
+				t.Errorf("%s: row %q is %d wide", a.Name, row, Width(row))
+				break
+			}
+		}
+	}
+}
+
+func TestArrangementEdits(t *testing.T) {
+	if _, err := ParsePanes("status, tasks"); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParsePanes("log"); err == nil {
+		t.Error("the log can't be moved into a side pane")
+	}
+	if _, err := ParsePanes("stauts"); err == nil {
+		t.Error("unknown panes should be rejected")
+	}
+
+	a := NextPreset("focus-log")
+	if a.Name != "dashboard" || NextPreset("zen").Name != "focus-log" {
+		t.Errorf("presets should cycle, got %s", a.Name)
+	}
+	a = a.Without(PaneDog)
+	if a.Has(PaneDog) || !a.Has(PaneTasks) {
+		t.Errorf("Without removed the wrong panes: %v", a.Visible())
+	}
+	a.ResizeSide(100)
+	if a.SideWidth != maxSideWidth {
+		t.Errorf("side width %d should be capped", a.SideWidth)
+	}
+}
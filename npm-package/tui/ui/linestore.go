@@ -0,0 +1,223 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stampLayout is the timestamp written before each line of the session file.
+const stampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// spillBlock is how many session-file lines share one offset index entry.
+// Reading a spilled line costs at most one block read, and the index costs
+// 8 bytes per block instead of per line.
+const spillBlock = 64
+
+// LineStore keeps the most recent lines in memory and the full session on
+// disk. Appending is O(1) regardless of history size; lines that have left
+// the in-memory window are read back from the session file in blocks.
+//
+// Lines are addressed by their index in the session, starting at 0. If the
+// session file can't be created, lines older than the scrollback are
+// dropped and First reports the oldest line still available.
+//
+// Each line is stamped with the time it was appended. The session file holds
+// one "<RFC 3339 time>\t<line>" record per line.
+type LineStore struct {
+	scrollback int
+	ring       []entry
+	total      int
+
+	path   string
+	file   *os.File
+	failed bool
+	size   int64
+	index  []int64 // byte offset of every spillBlock-th line
+	cached int     // block held in cache, -1 if none
+	cache  []entry
+}
+
+type entry struct {
+	at   time.Time
+	text string
+}
+
+// NewLineStore keeps scrollback lines in memory and, if path is non-empty,
+// writes the session to path. The file is created on first append.
+func NewLineStore(scrollback int, path string) *LineStore {
+	if scrollback < 1 {
+		scrollback = 1
+	}
+	return &LineStore{scrollback: scrollback, path: path, failed: path == "", cached: -1}
+}
+
+// Append adds a line to the end of the session, stamped with the current
+// time.
+func (s *LineStore) Append(line string) {
+	e := entry{at: time.Now(), text: line}
+	s.spill(e)
+	s.keep(e)
+}
+
+// ResumeLineStore is NewLineStore for a session file that already holds
+// lines, such as one a detached TUI left behind: they are read back in and
+// new lines are appended after them.
+func ResumeLineStore(scrollback int, path string) *LineStore {
+	s := NewLineStore(scrollback, path)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return s // the file is created on first append, as usual
+	}
+	r := bufio.NewReader(f)
+	for {
+		rec, err := r.ReadBytes('\n')
+		if err != nil {
+			break // a torn last record is dropped and overwritten
+		}
+		if s.total%spillBlock == 0 {
+			s.index = append(s.index, s.size)
+		}
+		s.size += int64(len(rec))
+		s.keep(parseRecord(bytes.TrimSuffix(rec, []byte("\n"))))
+	}
+	if err := f.Truncate(s.size); err != nil {
+		f.Close()
+		s.failed = true
+		return s
+	}
+	if _, err := f.Seek(s.size, io.SeekStart); err != nil {
+		f.Close()
+		s.failed = true
+		return s
+	}
+	s.file = f
+	return s
+}
+
+// keep adds e to the in-memory window.
+func (s *LineStore) keep(e entry) {
+	if len(s.ring) < s.scrollback {
+		s.ring = append(s.ring, e)
+	} else {
+		s.ring[s.total%s.scrollback] = e
+	}
+	s.total++
+}
+
+func (s *LineStore) spill(e entry) {
+	if s.failed {
+		return
+	}
+	if s.file == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+			s.failed = true
+			return
+		}
+		f, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+		if err != nil {
+			s.failed = true
+			return
+		}
+		s.file = f
+	}
+	if s.total%spillBlock == 0 {
+		s.index = append(s.index, s.size)
+	}
+	n, err := s.file.WriteString(Record(e.at, e.text))
+	s.size += int64(n)
+	if err != nil {
+		// Without a complete file we can't address old lines reliably.
+		s.failed = true
+		s.index = nil
+	}
+}
+
+// Record formats a line the way the session file holds it, for writers
+// that append to a session file without a LineStore.
+func Record(at time.Time, line string) string {
+	return at.Format(stampLayout) + "\t" + line + "\n"
+}
+
+func parseRecord(rec []byte) entry {
+	stamp, text, _ := bytes.Cut(rec, []byte("\t"))
+	at, _ := time.Parse(stampLayout, string(stamp))
+	return entry{at: at, text: string(text)}
+}
+
+// Total returns the number of lines ever appended.
+func (s *LineStore) Total() int {
+	return s.total
+}
+
+// First returns the index of the oldest line that can still be read.
+func (s *LineStore) First() int {
+	if !s.failed {
+		return 0
+	}
+	return max(0, s.total-len(s.ring))
+}
+
+// Path returns the session file, or "" if the session isn't on disk.
+func (s *LineStore) Path() string {
+	if s.failed {
+		return ""
+	}
+	return s.path
+}
+
+// Line returns line i, or "" if it is out of range.
+func (s *LineStore) Line(i int) string {
+	return s.entry(i).text
+}
+
+// Time returns when line i was appended, or the zero time if it is out of
+// range.
+func (s *LineStore) Time(i int) time.Time {
+	return s.entry(i).at
+}
+
+func (s *LineStore) entry(i int) entry {
+	if i < s.First() || i >= s.total {
+		return entry{}
+	}
+	if i >= s.total-len(s.ring) {
+		return s.ring[i%s.scrollback]
+	}
+	return s.spilled(i)
+}
+
+func (s *LineStore) spilled(i int) entry {
+	block, j := i/spillBlock, i%spillBlock
+	// The newest block may have grown since it was cached.
+	if block != s.cached || j >= len(s.cache) {
+		end := s.size
+		if block+1 < len(s.index) {
+			end = s.index[block+1]
+		}
+		buf := make([]byte, end-s.index[block])
+		if _, err := s.file.ReadAt(buf, s.index[block]); err != nil {
+			return entry{}
+		}
+		s.cache = s.cache[:0]
+		for _, l := range bytes.Split(bytes.TrimSuffix(buf, []byte("\n")), []byte("\n")) {
+			s.cache = append(s.cache, parseRecord(l))
+		}
+		s.cached = block
+	}
+	if j < len(s.cache) {
+		return s.cache[j]
+	}
+	return entry{}
+}
+
+// Close closes the session file.
+func (s *LineStore) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
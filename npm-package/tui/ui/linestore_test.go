@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLineStoreReadsSpilledLines(t *testing.T) {
+	s := NewLineStore(10, filepath.Join(t.TempDir(), "session.log"))
+	defer s.Close()
+	for i := range 1000 {
+		s.Append(fmt.Sprintf("line %d", i))
+	}
+	if s.First() != 0 || s.Total() != 1000 {
+		t.Fatalf("First/Total = %d/%d, want 0/1000", s.First(), s.Total())
+	}
+	for _, i := range []int{0, 63, 64, 500, 989, 990, 999} {
+		if got, want := s.Line(i), fmt.Sprintf("line %d", i); got != want {
+			t.Errorf("Line(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestLineStoreWithoutFileDropsOldLines(t *testing.T) {
+	s := NewLineStore(10, "")
+	for i := range 25 {
+		s.Append(fmt.Sprintf("line %d", i))
+	}
+	if s.First() != 15 {
+		t.Fatalf("First = %d, want 15", s.First())
+	}
+	if s.Line(14) != "" || s.Line(15) != "line 15" || s.Line(24) != "line 24" {
+		t.Errorf("unexpected lines: %q %q %q", s.Line(14), s.Line(15), s.Line(24))
+	}
+}
+
+func TestResumeLineStoreContinuesTheSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log")
+	s := NewLineStore(10, path)
+	for i := range 100 {
+		s.Append(fmt.Sprintf("line %d", i))
+	}
+	s.Close()
+	// A record cut off mid-write is dropped.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString("2026-01-02T03:04:05.000Z\ttorn")
+	f.Close()
+
+	r := ResumeLineStore(10, path)
+	defer r.Close()
+	r.Append("line 100")
+	if r.Total() != 101 {
+		t.Fatalf("Total = %d, want 101", r.Total())
+	}
+	for _, i := range []int{0, 64, 95, 100} {
+		if got, want := r.Line(i), fmt.Sprintf("line %d", i); got != want {
+			t.Errorf("Line(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+// BenchmarkWriteLine appends and renders one line on top of logs of
+// increasing size. ns/op should stay flat as the history grows.
+func BenchmarkWriteLine(b *testing.B) {
+	for _, size := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("history=%d", size), func(b *testing.B) {
+			store := NewLineStore(DefaultScrollback, filepath.Join(b.TempDir(), "session.log"))
+			defer store.Close()
+			vp := NewLogViewport(120, 40, store)
+			for i := range size {
+				vp.WriteLine(fmt.Sprintf("\x1b[32mLoop %d\x1b[0m doing things in src/app.ts:%d", i, i))
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				vp.WriteLine("a fresh line of runner output")
+				_ = vp.View()
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Ref is a file:line reference detected in a log line.
+type Ref struct {
+	Text string // the matched text as printed, e.g. src/app.ts:42
+	Path string // absolute path on disk
+	Line int
+	Col  int
+	Row  int // index of the log line the reference appears on
+}
+
+// URL returns a file:// URL suitable for an OSC 8 hyperlink.
+func (r Ref) URL() string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(r.Path)}
+	return u.String()
+}
+
+var refPattern = regexp.MustCompile(`(?:[A-Za-z]:)?[\w.~@+\-/\\]*[\w\-]\.[A-Za-z][A-Za-z0-9]*:(\d+)(?::(\d+))?`)
+
+// missTTL is how long a path found missing is taken to stay so. The agent
+// creates files as it goes, so a miss is looked at again after a while.
+const missTTL = 2 * time.Second
+
+// maxKnown caps the paths a RefFinder remembers; past it, they're all
+// forgotten.
+const maxKnown = 4096
+
+// RefFinder detects file references relative to a project directory. Only
+// paths that exist on disk are reported, so timestamps, URLs and other
+// colon-separated noise are ignored.
+type RefFinder struct {
+	Root  string
+	known map[string]time.Time // when a path was stat'ed; zero if it exists
+	now   func() time.Time
+}
+
+func NewRefFinder(root string) *RefFinder {
+	return &RefFinder{Root: root, known: map[string]time.Time{}, now: time.Now}
+}
+
+// Find returns the references on a single (possibly ANSI-coloured) line.
+func (f *RefFinder) Find(line string, row int) []Ref {
+	plain := ansi.Strip(line)
+	var refs []Ref
+	for _, m := range refPattern.FindAllStringSubmatchIndex(plain, -1) {
+		abs, ok := f.resolve(plain[m[0] : m[2]-1])
+		if !ok {
+			continue
+		}
+		r := Ref{Text: plain[m[0]:m[1]], Path: abs, Row: row}
+		r.Line, _ = strconv.Atoi(plain[m[2]:m[3]])
+		if m[4] >= 0 {
+			r.Col, _ = strconv.Atoi(plain[m[4]:m[5]])
+		}
+		refs = append(refs, r)
+	}
+	return refs
+}
+
+func (f *RefFinder) resolve(file string) (string, bool) {
+	p := file
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[2:])
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(f.Root, p)
+	}
+	missed, seen := f.known[p]
+	if seen && (missed.IsZero() || f.now().Sub(missed) < missTTL) {
+		return p, missed.IsZero()
+	}
+	info, err := os.Stat(p)
+	exists := err == nil && !info.IsDir()
+	if len(f.known) >= maxKnown {
+		clear(f.known)
+	}
+	if exists {
+		f.known[p] = time.Time{}
+	} else {
+		f.known[p] = f.now()
+	}
+	return p, exists
+}
+
+// Linkify wraps each reference in an OSC 8 hyperlink. Terminals without
+// OSC 8 support ignore the escape and show the plain text.
+func Linkify(line string, refs []Ref) string {
+	var b strings.Builder
+	for _, r := range refs {
+		i := strings.Index(line, r.Text)
+		if i < 0 {
+			// Colour codes split the reference; leave it unlinked.
+			continue
+		}
+		b.WriteString(line[:i])
+		b.WriteString(ansi.SetHyperlink(r.URL()) + r.Text + ansi.ResetHyperlink())
+		line = line[i+len(r.Text):]
+	}
+	b.WriteString(line)
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRefFinderFind(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"src/app.ts", "main.go", "README.md"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	abs := filepath.Join(root, "main.go")
+
+	tests := []struct {
+		name string
+		line string
+		want []Ref
+	}{
+		{"relative", "error in src/app.ts:42", []Ref{{Text: "src/app.ts:42", Path: filepath.Join(root, "src/app.ts"), Line: 42}}},
+		{"absolute", abs + ":7: undefined: x", []Ref{{Text: abs + ":7", Path: abs, Line: 7}}},
+		{"line and column", "main.go:12:5: syntax error", []Ref{{Text: "main.go:12:5", Path: abs, Line: 12, Col: 5}}},
+		{"coloured", "\x1b[31mmain.go:3\x1b[0m and \x1b[1mREADME.md:9:2\x1b[0m", []Ref{
+			{Text: "main.go:3", Path: abs, Line: 3},
+			{Text: "README.md:9:2", Path: filepath.Join(root, "README.md"), Line: 9, Col: 2},
+		}},
+		{"missing file", "see other.go:10", nil},
+		{"timestamp", "at 12:30:45 on v1.2:3", nil},
+		{"directory", "src.d:4", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewRefFinder(root).Find(tt.line, 3)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Find(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+			for i, want := range tt.want {
+				want.Row = 3
+				if got[i] != want {
+					t.Errorf("ref %d = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRefFinderLooksAgainAtMisses(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	f := NewRefFinder(root)
+	f.now = func() time.Time { return now }
+
+	if refs := f.Find("new.go:1", 0); len(refs) != 0 {
+		t.Fatalf("found %+v before the file exists", refs)
+	}
+	if err := os.WriteFile(filepath.Join(root, "new.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if refs := f.Find("new.go:1", 0); len(refs) != 0 {
+		t.Errorf("a miss should be remembered for a while, found %+v", refs)
+	}
+	now = now.Add(missTTL)
+	if refs := f.Find("new.go:1", 0); len(refs) != 1 {
+		t.Errorf("a miss should be looked at again after %v, found %+v", missTTL, refs)
+	}
+
+	for i := range maxKnown + 10 {
+		f.Find(fmt.Sprintf("gone%d.go:1", i), 0)
+	}
+	if len(f.known) > maxKnown {
+		t.Errorf("remembers %d paths, want at most %d", len(f.known), maxKnown)
+	}
+}
+
+func TestLinkify(t *testing.T) {
+	link := func(text, path string) string {
+		return ansi.SetHyperlink(Ref{Path: path}.URL()) + text + ansi.ResetHyperlink()
+	}
+	tests := []struct {
+		name string
+		line string
+		refs []Ref
+		want string
+	}{
+		{"none", "plain text", nil, "plain text"},
+		{"one", "at src/a.go:3 here", []Ref{{Text: "src/a.go:3", Path: "/p/src/a.go"}},
+			"at " + link("src/a.go:3", "/p/src/a.go") + " here"},
+		{"two", "a.go:1 b.go:2:3", []Ref{{Text: "a.go:1", Path: "/p/a.go"}, {Text: "b.go:2:3", Path: "/p/b.go"}},
+			link("a.go:1", "/p/a.go") + " " + link("b.go:2:3", "/p/b.go")},
+		{"coloured around", "\x1b[31ma.go:1\x1b[0m", []Ref{{Text: "a.go:1", Path: "/p/a.go"}},
+			"\x1b[31m" + link("a.go:1", "/p/a.go") + "\x1b[0m"},
+		{"coloured within", "\x1b[1ma.go\x1b[0m:1", []Ref{{Text: "a.go:1", Path: "/p/a.go"}},
+			"\x1b[1ma.go\x1b[0m:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Linkify(tt.line, tt.refs); got != tt.want {
+				t.Errorf("Linkify = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+	"vibepup-tui/theme"
+)
+
+// Fuzzy reports whether query's characters appear in s in order, ignoring
+// case, and scores the match: consecutive characters and matches at word
+// starts score higher, so "tt" prefers "Toggle timestamps" over "Settings".
+func Fuzzy(query, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	score, qi, prev := 0, 0, -2
+	runes := []rune(s)
+	for i, r := range runes {
+		if qi == len(q) {
+			break
+		}
+		if unicode.ToLower(r) != q[qi] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 2
+		}
+		if i == 0 || !unicode.IsLetter(runes[i-1]) {
+			score += 3
+		}
+		prev = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+// PaletteItem is one command offered by the palette.
+type PaletteItem struct {
+	Title string
+	Key   string // the binding shown next to it, if any
+}
+
+// CommandPalette is a filter-as-you-type list of commands.
+type CommandPalette struct {
+	Theme theme.Theme
+	Input textinput.Model
+
+	items    []PaletteItem
+	matches  []int // indexes into items, best first
+	selected int
+}
+
+// NewCommandPalette returns a palette over items with an empty query.
+func NewCommandPalette(th theme.Theme, items []PaletteItem) CommandPalette {
+	in := textinput.New()
+	in.Prompt = "> "
+	in.Placeholder = "type a command"
+	in.CharLimit = 64
+	p := CommandPalette{Theme: th, Input: in, items: items}
+	p.Filter()
+	return p
+}
+
+// Filter re-ranks the items against the current query.
+func (p *CommandPalette) Filter() {
+	type scored struct{ i, score int }
+	var hits []scored
+	for i, it := range p.items {
+		if s, ok := Fuzzy(p.Input.Value(), it.Title); ok {
+			hits = append(hits, scored{i, s})
+		}
+	}
+	sort.SliceStable(hits, func(a, b int) bool { return hits[a].score > hits[b].score })
+	p.matches = p.matches[:0]
+	for _, h := range hits {
+		p.matches = append(p.matches, h.i)
+	}
+	p.selected = 0
+}
+
+// Move changes the selection by delta, wrapping around.
+func (p *CommandPalette) Move(delta int) {
+	if n := len(p.matches); n > 0 {
+		p.selected = ((p.selected+delta)%n + n) % n
+	}
+}
+
+// Selected returns the index of the highlighted item.
+func (p CommandPalette) Selected() (int, bool) {
+	if len(p.matches) == 0 {
+		return 0, false
+	}
+	return p.matches[p.selected], true
+}
+
+// View renders the query and up to height-1 matches, width cells wide.
+func (p CommandPalette) View(width, height int) string {
+	title := lipgloss.NewStyle().Foreground(p.Theme.Foreground)
+	muted := lipgloss.NewStyle().Foreground(p.Theme.Muted)
+	active := lipgloss.NewStyle().Foreground(p.Theme.Highlight).Bold(true)
+
+	lines := []string{ClampWidth(p.Input.View(), width)}
+	if len(p.matches) == 0 {
+		lines = append(lines, muted.Render("no matching commands"))
+	}
+	rows := max(height-1, 1)
+	start := max(min(p.selected-rows+1, len(p.matches)-rows), 0)
+	for n, i := range p.matches[start:] {
+		if n == rows {
+			break
+		}
+		it := p.items[i]
+		style, marker := title, "  "
+		if start+n == p.selected {
+			style, marker = active, "▸ "
+		}
+		keyText := ClampWidth(it.Key, width/3)
+		name := ClampWidth(marker+it.Title, max(width-Width(keyText)-1, 0))
+		gap := spaces(width - Width(name) - Width(keyText))
+		lines = append(lines, style.Render(name)+gap+muted.Render(keyText))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,52 @@
+package ui
+
+import "testing"
+
+func TestFuzzy(t *testing.T) {
+	cases := []struct {
+		query, s string
+		ok       bool
+	}{
+		{"", "anything", true},
+		{"tgl", "Toggle line wrap", true},
+		{"WRAP", "Toggle line wrap", true},
+		{"pw", "Toggle line wrap", false},
+		{"xyz", "Settings", false},
+	}
+	for _, tc := range cases {
+		if _, ok := Fuzzy(tc.query, tc.s); ok != tc.ok {
+			t.Errorf("Fuzzy(%q, %q) = %v, want %v", tc.query, tc.s, ok, tc.ok)
+		}
+	}
+
+	word, _ := Fuzzy("tt", "Toggle timestamps")
+	scattered, _ := Fuzzy("tt", "Settings")
+	if word <= scattered {
+		t.Errorf("word-start matches should rank higher: %d <= %d", word, scattered)
+	}
+}
+
+func TestCommandPaletteFilters(t *testing.T) {
+	p := NewCommandPalette(themeForTest, []PaletteItem{
+		{Title: "Settings", Key: ","},
+		{Title: "Toggle timestamps", Key: "T"},
+		{Title: "Quit", Key: "q"},
+	})
+	if i, ok := p.Selected(); !ok || i != 0 {
+		t.Fatalf("empty query should list everything in order, got %d", i)
+	}
+	p.Input.SetValue("tt")
+	p.Filter()
+	if i, _ := p.Selected(); i != 1 {
+		t.Errorf("best match for tt should be Toggle timestamps, got %d", i)
+	}
+	p.Move(1)
+	if i, _ := p.Selected(); i != 0 {
+		t.Errorf("second match should be Settings, got %d", i)
+	}
+	p.Input.SetValue("zzz")
+	p.Filter()
+	if _, ok := p.Selected(); ok {
+		t.Error("nothing should match zzz")
+	}
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"vibepup-tui/problems"
+	"vibepup-tui/theme"
+)
+
+// ProblemsPanel renders the deduplicated error list extracted from the log.
+type ProblemsPanel struct {
+	Theme theme.Theme
+}
+
+// Render draws at most height lines. Problems from the current iteration are
+// highlighted; ones the agent has since fixed are muted.
+func (p ProblemsPanel) Render(set *problems.Set, width, height int) string {
+	if set == nil || height <= 0 {
+		return ""
+	}
+	title := lipgloss.NewStyle().Foreground(p.Theme.Accent).Bold(true)
+	open := lipgloss.NewStyle().Foreground(p.Theme.Foreground)
+	closed := lipgloss.NewStyle().Foreground(p.Theme.Muted)
+	loc := lipgloss.NewStyle().Foreground(p.Theme.Highlight)
+
+	lines := []string{title.Render(ClampWidth(fmt.Sprintf("PROBLEMS %d open / %d seen (loop %d)", set.Open(), set.Len(), set.Iteration()), width))}
+	for _, e := range set.List() {
+		if len(lines) >= height {
+			break
+		}
+		style := open
+		if !e.Open(set.Iteration()) {
+			style = closed
+		}
+		repeat := "  "
+		if e.Iterations > 1 {
+			repeat = fmt.Sprintf("×%d", e.Iterations)
+		}
+		where := e.Location()
+		if where == "" {
+			where = e.Source
+		}
+		row := ClampWidth(fmt.Sprintf("%s %s  %s", repeat, where, e.Message), width)
+		if !e.Open(set.Iteration()) {
+			lines = append(lines, closed.Render(row))
+			continue
+		}
+		head := ClampWidth(repeat+" "+where, width)
+		lines = append(lines, loc.Render(head)+style.Render(strings.TrimPrefix(row, head)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Match is a search hit on a single log line, as byte offsets into the
+// line's plain (ANSI-stripped) text.
+type Match struct {
+	Row        int
+	Start, End int
+}
+
+type search struct {
+	query   string
+	regex   bool
+	re      *regexp.Regexp
+	matches []Match
+	current int
+}
+
+// compileQuery builds the matcher for a query. Plain queries are literal;
+// both kinds are smart-case, so an all-lowercase query ignores case.
+func compileQuery(query string, regex bool) (*regexp.Regexp, error) {
+	pattern := query
+	if !regex {
+		pattern = regexp.QuoteMeta(query)
+	}
+	if strings.ToLower(query) == query {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func (s *search) scan(row int, line string) {
+	plain := ansi.Strip(line)
+	for _, loc := range s.re.FindAllStringIndex(plain, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		s.matches = append(s.matches, Match{Row: row, Start: loc[0], End: loc[1]})
+	}
+}
+
+// onRowBefore returns the number of matches on lines before row.
+func (s *search) onRowBefore(row int) int {
+	return sort.Search(len(s.matches), func(i int) bool { return s.matches[i].Row >= row })
+}
+
+// onRow returns the range of matches on line row.
+func (s *search) onRow(row int) (int, int) {
+	return s.onRowBefore(row), s.onRowBefore(row + 1)
+}
+
+// SetSearch searches the whole session buffer for query and jumps to the
+// last match at or above the current view. An empty query clears the search.
+// A plain query typed on from the last one can only match where that one
+// did, so only those lines are searched again.
+func (l *LogViewport) SetSearch(query string, regex bool) error {
+	if query == "" {
+		l.ClearSearch()
+		return nil
+	}
+	re, err := compileQuery(query, regex)
+	if err != nil {
+		return err
+	}
+	prev := l.search
+	l.search = &search{query: query, regex: regex, re: re}
+	if prev != nil && !regex && !prev.regex && strings.HasPrefix(query, prev.query) {
+		for i, m := range prev.matches {
+			if i == 0 || m.Row != prev.matches[i-1].Row {
+				l.search.scan(m.Row, l.store.Line(m.Row))
+			}
+		}
+	} else {
+		for i := l.store.First(); i < l.store.Total(); i++ {
+			l.search.scan(i, l.store.Line(i))
+		}
+	}
+	_, to := l.visible()
+	l.search.current = max(0, l.search.onRowBefore(to)-1)
+	l.showMatch()
+	return nil
+}
+
+// ClearSearch removes the search and its highlights.
+func (l *LogViewport) ClearSearch() {
+	l.search = nil
+}
+
+// Searching reports whether a search is active.
+func (l *LogViewport) Searching() bool {
+	return l.search != nil
+}
+
+// SearchQuery returns the active query and whether it is a regex.
+func (l *LogViewport) SearchQuery() (string, bool) {
+	if l.search == nil {
+		return "", false
+	}
+	return l.search.query, l.search.regex
+}
+
+// MatchStatus returns the 1-based index of the current match and the total.
+func (l *LogViewport) MatchStatus() (int, int) {
+	if l.search == nil || len(l.search.matches) == 0 {
+		return 0, 0
+	}
+	return l.search.current + 1, len(l.search.matches)
+}
+
+// NextMatch moves delta matches forward (or backward), wrapping around.
+func (l *LogViewport) NextMatch(delta int) {
+	if l.search == nil || len(l.search.matches) == 0 {
+		return
+	}
+	n := len(l.search.matches)
+	l.search.current = ((l.search.current+delta)%n + n) % n
+	l.showMatch()
+}
+
+func (l *LogViewport) showMatch() {
+	if l.search != nil && len(l.search.matches) > 0 {
+		row := l.search.matches[l.search.current].Row
+		l.unfold(row)
+		if from, to := l.visible(); row < from || row >= to {
+			l.SetYOffset(row)
+			l.ScrollBy(-l.height / 2)
+		}
+	}
+}
+
+// highlight renders a line's plain text with its matches styled. offset is
+// the index of the line's first match in the search's match list.
+func (l *LogViewport) highlight(line string, matches []Match, offset int) string {
+	plain := ansi.Strip(line)
+	hit := lipgloss.NewStyle().Background(l.Theme.Highlight).Foreground(l.Theme.Background)
+	cur := hit.Background(l.Theme.Accent).Bold(true)
+
+	var b strings.Builder
+	pos := 0
+	for i, m := range matches {
+		b.WriteString(plain[pos:m.Start])
+		style := hit
+		if offset+i == l.search.current {
+			style = cur
+		}
+		b.WriteString(style.Render(plain[m.Start:m.End]))
+		pos = m.End
+	}
+	b.WriteString(plain[pos:])
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchFindsSpilledLines(t *testing.T) {
+	store := NewLineStore(10, filepath.Join(t.TempDir(), "session.log"))
+	defer store.Close()
+	vp := NewLogViewport(40, 5, store)
+	for i := range 200 {
+		if i%50 == 7 {
+			vp.WriteLine(fmt.Sprintf("\x1b[31mError\x1b[0m at step %d", i))
+		} else {
+			vp.WriteLine(fmt.Sprintf("line %d", i))
+		}
+	}
+
+	if err := vp.SetSearch("error", false); err != nil {
+		t.Fatal(err)
+	}
+	if cur, total := vp.MatchStatus(); cur != 4 || total != 4 {
+		t.Fatalf("MatchStatus = %d/%d, want 4/4", cur, total)
+	}
+	vp.NextMatch(1)
+	if cur, _ := vp.MatchStatus(); cur != 1 {
+		t.Errorf("NextMatch should wrap to the first match, at %d", cur)
+	}
+	if from, to := vp.visible(); 7 < from || 7 >= to {
+		t.Errorf("the first match, in a spilled line, isn't in view: %d-%d", from, to)
+	}
+	vp.NextMatch(-2)
+	if cur, _ := vp.MatchStatus(); cur != 3 {
+		t.Errorf("NextMatch(-2) from the first should wrap to 3, at %d", cur)
+	}
+
+	// Case matters once the query has a capital; typing on narrows it.
+	for _, q := range []string{"Error", "Error at step 1", "Error at step 10"} {
+		if err := vp.SetSearch(q, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, total := vp.MatchStatus(); total != 1 {
+		t.Errorf("%q: %d matches, want 1", "Error at step 10", total)
+	}
+	if err := vp.SetSearch("ERROR", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, total := vp.MatchStatus(); total != 0 {
+		t.Errorf("%q: %d matches, want none", "ERROR", total)
+	}
+	if err := vp.SetSearch(`step \d+7$`, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, total := vp.MatchStatus(); total != 3 {
+		t.Errorf("regex: %d matches, want 3", total)
+	}
+}
+
+func TestSearchRegexError(t *testing.T) {
+	vp := NewLogViewport(40, 5, nil)
+	vp.WriteLine("a (b) c")
+	if err := vp.SetSearch("(b", true); err == nil {
+		t.Error("an invalid regex should be reported")
+	}
+	if err := vp.SetSearch("(b", false); err != nil {
+		t.Errorf("a plain query is literal: %v", err)
+	}
+	if cur, total := vp.MatchStatus(); cur != 1 || total != 1 {
+		t.Errorf("MatchStatus = %d/%d, want 1/1", cur, total)
+	}
+	if err := vp.SetSearch("", false); err != nil || vp.Searching() {
+		t.Errorf("an empty query should clear the search: %v", err)
+	}
+	if cur, total := vp.MatchStatus(); cur != 0 || total != 0 {
+		t.Errorf("MatchStatus without a search = %d/%d", cur, total)
+	}
+}
+
+func TestSearchKeepsFollowingAtTheBottom(t *testing.T) {
+	vp := NewLogViewport(40, 5, nil)
+	for i := range 20 {
+		vp.WriteLine(fmt.Sprintf("line %d", i))
+	}
+	if err := vp.SetSearch("line 19", false); err != nil {
+		t.Fatal(err)
+	}
+	vp.WriteLine("line 20")
+	if !vp.AtBottom() {
+		t.Errorf("new output should still scroll the view, offset %d", vp.YOffset())
+	}
+
+	if err := vp.SetSearch("line 3", false); err != nil {
+		t.Fatal(err)
+	}
+	at := vp.YOffset()
+	vp.WriteLine("line 21")
+	if vp.YOffset() != at {
+		t.Errorf("a match above should hold the view at %d, moved to %d", at, vp.YOffset())
+	}
+}
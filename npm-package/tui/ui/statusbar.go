@@ -1,28 +1,72 @@
 package ui
 
 import (
-	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"vibepup-tui/theme"
 )
 
+// Segment is one item of the status bar.
+type Segment struct {
+	Text string
+	// Priority decides what survives a narrow terminal: the lowest
+	// priority segments are dropped first.
+	Priority int
+	// Strong renders the segment in the accent colour.
+	Strong bool
+}
+
+// StatusBar is the one-line run summary at the bottom of the screen.
 type StatusBar struct {
 	Theme theme.Theme
 }
 
-func (s StatusBar) Render(left, right string, width int) string {
-	style := lipgloss.NewStyle().Foreground(s.Theme.Foreground).Background(s.Theme.Muted)
-	spacer := width - lipgloss.Width(left) - lipgloss.Width(right)
-	if spacer < 1 {
-		spacer = 1
+// statusSeparator goes between segments.
+const statusSeparator = " │ "
+
+// Render lays the segments out left to right in a bar width cells wide,
+// dropping low-priority segments until the rest fit.
+func (s StatusBar) Render(segments []Segment, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	kept := append([]Segment(nil), segments...)
+	for len(kept) > 1 && barWidth(kept) > width {
+		drop := 0
+		for i, seg := range kept {
+			// On a tie drop the rightmost, so the bar shrinks from the end.
+			if seg.Priority <= kept[drop].Priority {
+				drop = i
+			}
+		}
+		kept = append(kept[:drop], kept[drop+1:]...)
+	}
+
+	bar := lipgloss.NewStyle().Foreground(s.Theme.Foreground).Background(s.Theme.Muted)
+	strong := bar.Foreground(s.Theme.Accent).Bold(true)
+	sep := bar.Foreground(s.Theme.Background)
+	parts := make([]string, len(kept))
+	for i, seg := range kept {
+		style := bar
+		if seg.Strong {
+			style = strong
+		}
+		parts[i] = style.Render(seg.Text)
+	}
+	line := ClampWidth(bar.Render(" ")+strings.Join(parts, sep.Render(statusSeparator)), width)
+	return line + bar.Render(spaces(width-Width(line)))
+}
+
+// barWidth is the width of the segments joined, with the leading space.
+func barWidth(segments []Segment) int {
+	w := 1 + Width(statusSeparator)*(len(segments)-1)
+	for _, seg := range segments {
+		w += Width(seg.Text)
 	}
-	return style.Render(fmt.Sprintf("%s%s%s", left, spaces(spacer), right))
+	return w
 }
 
 func spaces(n int) string {
-	if n < 0 {
-		n = 0
-	}
-	return fmt.Sprintf("%*s", n, "")
+	return strings.Repeat(" ", max(n, 0))
 }
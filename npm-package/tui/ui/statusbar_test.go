@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestStatusBarDropsLowPriority(t *testing.T) {
+	segs := []Segment{
+		{Text: "BUILD", Priority: 9},
+		{Text: "loop 3/5", Priority: 8},
+		{Text: "openai/gpt-5.2", Priority: 3},
+		{Text: "tasks 2/7", Priority: 7},
+		{Text: "pid 4242", Priority: 1},
+	}
+	bar := StatusBar{Theme: themeForTest}
+
+	wide := ansi.Strip(bar.Render(segs, 80))
+	if Width(wide) != 80 || !strings.Contains(wide, "pid 4242") {
+		t.Errorf("everything should fit in 80 cells: %q", wide)
+	}
+
+	narrow := ansi.Strip(bar.Render(segs, 30))
+	if Width(narrow) != 30 {
+		t.Errorf("bar should fill the width, got %d", Width(narrow))
+	}
+	if !strings.Contains(narrow, "BUILD │ loop 3/5 │ tasks 2/7") || strings.Contains(narrow, "pid") || strings.Contains(narrow, "gpt") {
+		t.Errorf("low-priority segments should go first: %q", narrow)
+	}
+
+	if tiny := ansi.Strip(bar.Render(segs, 4)); Width(tiny) != 4 {
+		t.Errorf("a lone segment should be cut to fit: %q", tiny)
+	}
+}
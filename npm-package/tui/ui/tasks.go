@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"vibepup-tui/tasks"
+	"vibepup-tui/theme"
+)
+
+// TasksPanel renders the PRD checklist with the runner's next task marked.
+type TasksPanel struct {
+	Theme theme.Theme
+	// ASCII swaps the check marks for plain brackets.
+	ASCII bool
+	// Offset scrolls the list; the next task is kept in view by default.
+	Offset int
+}
+
+// Render draws at most height lines.
+func (p TasksPanel) Render(list tasks.List, width, height int) string {
+	if height <= 0 {
+		return ""
+	}
+	title := lipgloss.NewStyle().Foreground(p.Theme.Accent).Bold(true)
+	done := lipgloss.NewStyle().Foreground(p.Theme.Muted)
+	next := lipgloss.NewStyle().Foreground(p.Theme.Highlight).Bold(true)
+	todo := lipgloss.NewStyle().Foreground(p.Theme.Foreground)
+
+	lines := []string{title.Render(ClampWidth(fmt.Sprintf("TASKS %d/%d", list.Done(), list.Total()), width))}
+	if list.Total() == 0 {
+		return strings.Join(append(lines, done.Render(ClampWidth("no "+tasks.File+" checklist", width))), "\n")
+	}
+
+	marks := [3]string{"✓", "▶", "○"}
+	if p.ASCII {
+		marks = [3]string{"[x]", "[>]", "[ ]"}
+	}
+	current, _ := list.Next()
+	start := min(max(p.Offset, 0), max(list.Total()-(height-1), 0))
+	for _, t := range list.Tasks[start:] {
+		if len(lines) >= height {
+			break
+		}
+		mark, style := marks[2], todo
+		switch {
+		case t.Done:
+			mark, style = marks[0], done
+		case t.Line == current.Line:
+			mark, style = marks[1], next
+		}
+		lines = append(lines, style.Render(ClampWidth(mark+" "+t.Text, width)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NextOffset returns the offset that shows the next task near the top.
+func (p TasksPanel) NextOffset(list tasks.List) int {
+	t, ok := list.Next()
+	if !ok {
+		return 0
+	}
+	for i, u := range list.Tasks {
+		if u.Line == t.Line {
+			return max(i-1, 0)
+		}
+	}
+	return 0
+}
@@ -1,58 +1,464 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"vibepup-tui/theme"
 )
 
-type Layout struct {
-	HeaderHeight int
-	FooterHeight int
-	Width        int
-	Height       int
-}
+// DefaultScrollback is how many log lines are kept in memory when no
+// scrollback is configured.
+const DefaultScrollback = 5000
 
+// LogViewport shows the tail of a LineStore. Only the visible window is
+// rendered, so the cost of a new line doesn't grow with the session.
 type LogViewport struct {
-	Model      viewport.Model
-	Content    *strings.Builder
+	KeyMap viewport.KeyMap
+	// AutoScroll follows new output while the view is at the bottom.
+	// Scrolling up pauses it and counts the lines that arrive meanwhile.
 	AutoScroll bool
+	// Wrap soft-wraps long lines; otherwise they are cut at the edge and
+	// can be scrolled horizontally.
+	Wrap bool
+	// Timestamps shows the time each line arrived in a gutter.
+	Timestamps bool
+	// Links detects file:line references; nil disables detection.
+	Links *RefFinder
+	// Hyperlinks emits detected references as OSC 8 links.
+	Hyperlinks bool
+	// Theme colours search highlights.
+	Theme theme.Theme
+	// Palette remaps the basic ANSI colours of the output; nil keeps them.
+	Palette *Palette
+
+	store   *LineStore
+	width   int
+	height  int
+	yOffset int // index of the top visible line
+	xOffset int // cells scrolled right when not wrapping
+	// detached is set once the user scrolls away from the bottom; unseen
+	// counts the lines written since.
+	detached bool
+	unseen   int
+
+	linkMode bool
+	selected Ref
+	search   *search
+
+	blocks    []block
+	expandAll bool
+	blankRun  int // blank lines at the end of the open block
 }
 
-func NewLogViewport(width, height int) LogViewport {
-	vp := viewport.New(width, height)
-	vp.YPosition = 0
-	vp.HighPerformanceRendering = false
+var selectedRefStyle = lipgloss.NewStyle().Reverse(true)
+
+// NewLogViewport creates a viewport over store. A nil store keeps
+// DefaultScrollback lines in memory only.
+func NewLogViewport(width, height int, store *LineStore) LogViewport {
+	if store == nil {
+		store = NewLineStore(DefaultScrollback, "")
+	}
 	return LogViewport{
-		Model:      vp,
-		Content:    &strings.Builder{},
+		KeyMap:     viewport.DefaultKeyMap(),
 		AutoScroll: true,
+		Hyperlinks: true,
+		store:      store,
+		width:      width,
+		height:     height,
 	}
 }
 
 func (l *LogViewport) SetSize(width, height int) {
-	l.Model.Width = width
-	l.Model.Height = height
+	l.width = width
+	l.height = max(height, 0)
+	if l.following() {
+		l.GotoBottom()
+	} else {
+		l.SetYOffset(l.yOffset)
+	}
+}
+
+// Store returns the line store behind the viewport.
+func (l *LogViewport) Store() *LineStore {
+	return l.store
 }
 
+// WriteLine appends output to the log; embedded newlines start new lines.
+// Each line is sanitized so cursor movement in the output can't disturb the
+// layout.
 func (l *LogViewport) WriteLine(line string) {
-	l.Content.WriteString(line + "\n")
-	l.Model.SetContent(l.Content.String())
-	if l.AutoScroll {
-		l.Model.GotoBottom()
+	parts := strings.Split(line, "\n")
+	for _, part := range parts {
+		part = Sanitize(part)
+		l.endBlockAtProse(strings.TrimSpace(ansi.Strip(part)) == "")
+		l.store.Append(part)
+		if l.search != nil {
+			l.search.scan(l.store.Total()-1, part)
+		}
+	}
+	l.pruneBlocks()
+	// Hold the view still while the user is picking a link or has scrolled
+	// away, e.g. to a match.
+	if l.following() {
+		l.GotoBottom()
+		return
+	}
+	l.unseen += len(parts)
+	// Lines may have fallen out of scrollback under the view.
+	l.SetYOffset(l.yOffset)
+}
+
+// following reports whether new output should scroll the view. A search
+// that jumps to a match above scrolls away from the bottom, which holds the
+// view; one left at the bottom keeps following.
+func (l *LogViewport) following() bool {
+	return l.AutoScroll && !l.detached && !l.linkMode
+}
+
+// NewLines returns how many lines arrived while the view was scrolled away
+// from the bottom.
+func (l *LogViewport) NewLines() int {
+	return l.unseen
+}
+
+// YOffset returns the index of the top visible line.
+func (l *LogViewport) YOffset() int {
+	return l.yOffset
+}
+
+// SetYOffset scrolls so line n is at the top, within the available lines.
+// Reaching the bottom resumes following new output.
+func (l *LogViewport) SetYOffset(n int) {
+	l.yOffset = min(max(n, l.store.First()), l.maxOffset())
+	if l.hidden(l.yOffset) {
+		k, _ := l.blockAt(l.yOffset)
+		l.yOffset = l.blocks[k].header
+	}
+	l.detached = !l.AtBottom()
+	if !l.detached {
+		l.unseen = 0
+	}
+}
+
+// maxOffset is the top line when the newest line sits at the bottom,
+// counting collapsed blocks as their header only.
+func (l *LogViewport) maxOffset() int {
+	i := l.lastVisible()
+	for n := 1; n < l.height && i > l.store.First(); n++ {
+		i = l.prevVisible(i)
 	}
+	return max(i, l.store.First())
+}
+
+// AtBottom reports whether the newest line is visible.
+func (l *LogViewport) AtBottom() bool {
+	return l.yOffset >= l.maxOffset()
+}
+
+// GotoBottom jumps to the newest line and resumes following output.
+func (l *LogViewport) GotoBottom() {
+	l.yOffset = l.maxOffset()
+	l.detached = false
+	l.unseen = 0
+}
+
+func (l *LogViewport) GotoTop() {
+	l.yOffset = l.store.First()
+}
+
+// ScrollBy moves the view n visible lines down, or up if n is negative.
+func (l *LogViewport) ScrollBy(n int) {
+	i := l.yOffset
+	for ; n > 0 && i < l.store.Total(); n-- {
+		i = l.nextVisible(i)
+	}
+	for ; n < 0 && i > l.store.First(); n++ {
+		i = l.prevVisible(i)
+	}
+	l.SetYOffset(i)
+}
+
+// ScrollRight moves the view n cells right, or left if n is negative. It
+// has no effect while wrapping.
+func (l *LogViewport) ScrollRight(n int) {
+	if l.Wrap {
+		return
+	}
+	l.xOffset = max(l.xOffset+n, 0)
+}
+
+// XOffset returns how many cells the view is scrolled right.
+func (l *LogViewport) XOffset() int {
+	return l.xOffset
+}
+
+// visible returns the half-open range of line indexes on screen. Lines in
+// collapsed blocks within the range are not shown.
+func (l *LogViewport) visible() (int, int) {
+	i := l.yOffset
+	for n := 0; n < l.height && i < l.store.Total(); n++ {
+		i = l.nextVisible(i)
+	}
+	return l.yOffset, i
 }
 
 func (l *LogViewport) Update(msg tea.Msg) (LogViewport, tea.Cmd) {
-	var cmd tea.Cmd
-	l.Model, cmd = l.Model.Update(msg)
-	return *l, cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, l.KeyMap.PageDown):
+			l.ScrollBy(l.height)
+		case key.Matches(msg, l.KeyMap.PageUp):
+			l.ScrollBy(-l.height)
+		case key.Matches(msg, l.KeyMap.HalfPageDown):
+			l.ScrollBy(l.height / 2)
+		case key.Matches(msg, l.KeyMap.HalfPageUp):
+			l.ScrollBy(-l.height / 2)
+		case key.Matches(msg, l.KeyMap.Down):
+			l.ScrollBy(1)
+		case key.Matches(msg, l.KeyMap.Up):
+			l.ScrollBy(-1)
+		case key.Matches(msg, l.KeyMap.Right):
+			l.ScrollRight(horizontalStep)
+		case key.Matches(msg, l.KeyMap.Left):
+			l.ScrollRight(-horizontalStep)
+		case msg.String() == "home":
+			l.GotoTop()
+		case msg.String() == "end":
+			l.GotoBottom()
+		}
+	case tea.MouseMsg:
+		switch msg.Button {
+		case tea.MouseButtonWheelDown:
+			l.ScrollBy(3)
+		case tea.MouseButtonWheelUp:
+			l.ScrollBy(-3)
+		}
+	}
+	return *l, nil
 }
 
+// gutterWidth is the width of the timestamp gutter, "15:04:05 ".
+const gutterWidth = 9
+
+// horizontalStep is how many cells left/right scrolls when not wrapping.
+const horizontalStep = 8
+
+// View renders the visible window, padded to the viewport height.
 func (l *LogViewport) View() string {
-	return l.Model.View()
+	if l.height == 0 {
+		return ""
+	}
+	width := l.width
+	if l.Timestamps {
+		width = max(width-gutterWidth, 1)
+	}
+	var rows []string
+	if l.Wrap && l.AtBottom() {
+		// Anchor to the bottom so the newest line is always fully visible.
+		for i := l.lastVisible(); l.store.Total() > 0 && i >= l.store.First() && len(rows) < l.height; i = l.prevVisible(i) {
+			rows = append(l.rows(i, width), rows...)
+		}
+		rows = rows[max(0, len(rows)-l.height):]
+	} else {
+		for i := l.yOffset; i < l.store.Total() && len(rows) < l.height; i = l.nextVisible(i) {
+			rows = append(rows, l.rows(i, width)...)
+		}
+		rows = rows[:min(len(rows), l.height)]
+	}
+	for len(rows) < l.height {
+		rows = append(rows, "")
+	}
+	if l.unseen > 0 {
+		rows[len(rows)-1] = l.withBadge(rows[len(rows)-1])
+	}
+	return strings.Join(rows, "\n")
+}
+
+// rows renders line i as one screen row, or several when wrapping.
+func (l *LogViewport) rows(i, width int) []string {
+	line := l.foldMarker(i, l.renderLine(i))
+	var rows []string
+	if l.Wrap {
+		rows = Wrap(line, width)
+	} else {
+		rows = []string{ansi.Cut(line, l.xOffset, l.xOffset+width)}
+	}
+	gutter := lipgloss.NewStyle().Foreground(l.Theme.Muted)
+	for j, row := range rows {
+		if strings.Contains(row, "\x1b[") {
+			// Don't let an unterminated style bleed into the next row.
+			row += ansi.ResetStyle
+		}
+		if l.Timestamps {
+			stamp := strings.Repeat(" ", gutterWidth)
+			if at := l.store.Time(i); j == 0 && !at.IsZero() {
+				stamp = at.Format("15:04:05") + " "
+			}
+			row = gutter.Render(stamp) + row
+		}
+		rows[j] = row
+	}
+	return rows
+}
+
+// withBadge overlays the "new lines" badge on the right of row.
+func (l *LogViewport) withBadge(row string) string {
+	text := fmt.Sprintf(" ↓ %d new lines ", l.unseen)
+	if l.unseen == 1 {
+		text = " ↓ 1 new line "
+	}
+	badge := lipgloss.NewStyle().
+		Foreground(l.Theme.Background).
+		Background(l.Theme.Highlight).
+		Bold(true).
+		Render(text)
+	room := max(l.width-Width(badge), 0)
+	row = ClampWidth(row, room)
+	return row + strings.Repeat(" ", room-Width(row)) + badge
+}
+
+func (l *LogViewport) renderLine(i int) string {
+	line := l.store.Line(i)
+	if l.Palette != nil {
+		line = Restyle(line, l.Palette)
+	}
+	if l.search != nil {
+		if first, last := l.search.onRow(i); last > first {
+			return l.highlight(line, l.search.matches[first:last], first)
+		}
+	}
+	if l.Links == nil {
+		return line
+	}
+	if refs := l.Links.Find(line, i); len(refs) > 0 {
+		line = l.decorate(line, refs)
+	}
+	return line
+}
+
+// decorate links the references on a line and highlights the selected one.
+func (l *LogViewport) decorate(line string, refs []Ref) string {
+	if l.linkMode && l.selected.Row == refs[0].Row {
+		if i := strings.Index(line, l.selected.Text); i >= 0 {
+			styled := selectedRefStyle.Render(l.selected.Text)
+			// The selection already stands out; skip linking the rest.
+			return line[:i] + styled + line[i+len(l.selected.Text):]
+		}
+	}
+	if l.Hyperlinks {
+		line = Linkify(line, refs)
+	}
+	return line
+}
+
+// refsOn returns the references on line i.
+func (l *LogViewport) refsOn(i int) []Ref {
+	if l.Links == nil {
+		return nil
+	}
+	return l.Links.Find(l.store.Line(i), i)
+}
+
+// LinkMode reports whether reference selection is active.
+func (l *LogViewport) LinkMode() bool {
+	return l.linkMode
+}
+
+// ToggleLinkMode enters or leaves reference selection. Entering selects the
+// last reference at or above the bottom of the visible region. It returns
+// false if there is nothing to select.
+func (l *LogViewport) ToggleLinkMode() bool {
+	if l.linkMode {
+		l.linkMode = false
+		return false
+	}
+	_, to := l.visible()
+	for i := l.prevVisible(to); i >= l.store.First(); i = l.prevVisible(i) {
+		if refs := l.refsOn(i); len(refs) > 0 {
+			l.selected = refs[len(refs)-1]
+			l.linkMode = true
+			l.reveal(i)
+			return true
+		}
+	}
+	return false
+}
+
+// MoveSelection moves the reference cursor by delta references and scrolls
+// it into view. The cursor stops at the first and last reference.
+func (l *LogViewport) MoveSelection(delta int) {
+	if !l.linkMode {
+		return
+	}
+	step := 1
+	if delta < 0 {
+		step, delta = -1, -delta
+	}
+	for ; delta > 0; delta-- {
+		next, ok := l.adjacentRef(l.selected, step)
+		if !ok {
+			break
+		}
+		l.selected = next
+	}
+	l.reveal(l.selected.Row)
+}
+
+// adjacentRef finds the reference after r (step 1) or before it (step -1),
+// scanning the visible log lazily rather than keeping every reference in
+// memory.
+func (l *LogViewport) adjacentRef(r Ref, step int) (Ref, bool) {
+	refs := l.refsOn(r.Row)
+	for i := range refs {
+		if refs[i].Text == r.Text {
+			if j := i + step; j >= 0 && j < len(refs) {
+				return refs[j], true
+			}
+			break
+		}
+	}
+	next := l.nextVisible
+	if step < 0 {
+		next = l.prevVisible
+	}
+	for row := next(r.Row); row >= l.store.First() && row < l.store.Total(); row = next(row) {
+		refs := l.refsOn(row)
+		if len(refs) == 0 {
+			continue
+		}
+		if step > 0 {
+			return refs[0], true
+		}
+		return refs[len(refs)-1], true
+	}
+	return Ref{}, false
+}
+
+// reveal scrolls the least amount needed to show line row, expanding the
+// block it is folded into.
+func (l *LogViewport) reveal(row int) {
+	l.unfold(row)
+	if _, to := l.visible(); row < l.yOffset {
+		l.SetYOffset(row)
+	} else if row >= to {
+		l.SetYOffset(row)
+		l.ScrollBy(1 - l.height)
+	}
+}
+
+// SelectedRef returns the reference under the cursor in link mode.
+func (l *LogViewport) SelectedRef() (Ref, bool) {
+	if !l.linkMode {
+		return Ref{}, false
+	}
+	return l.selected, true
 }
 
 var (
@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestViewportPausesWhenScrolledUp(t *testing.T) {
+	vp := NewLogViewport(40, 5, nil)
+	for i := range 20 {
+		vp.WriteLine(fmt.Sprintf("line %d", i))
+	}
+	if !vp.AtBottom() || vp.YOffset() != 15 {
+		t.Fatalf("should follow output, offset %d", vp.YOffset())
+	}
+
+	vp.ScrollBy(-3)
+	for i := 20; i < 57; i++ {
+		vp.WriteLine(fmt.Sprintf("line %d", i))
+	}
+	if vp.YOffset() != 12 {
+		t.Errorf("scrolled-up view moved to %d", vp.YOffset())
+	}
+	if vp.NewLines() != 37 {
+		t.Errorf("NewLines = %d, want 37", vp.NewLines())
+	}
+	if view := ansi.Strip(vp.View()); !strings.Contains(view, "↓ 37 new lines") {
+		t.Errorf("badge missing from view:\n%s", view)
+	}
+
+	vp.GotoBottom()
+	vp.WriteLine("line 57")
+	if !vp.AtBottom() || vp.NewLines() != 0 {
+		t.Errorf("jumping to live should resume following")
+	}
+}
+
+func TestViewportWrapKeepsNewestLineVisible(t *testing.T) {
+	vp := NewLogViewport(10, 3, nil)
+	vp.Wrap = true
+	vp.WriteLine("short")
+	vp.WriteLine("a much longer line that wraps")
+	rows := strings.Split(ansi.Strip(vp.View()), "\n")
+	if len(rows) != 3 || !strings.Contains(rows[2], "wraps") {
+		t.Errorf("unexpected rows %q", rows)
+	}
+	for _, r := range rows {
+		if Width(r) > 10 {
+			t.Errorf("row %q exceeds the width", r)
+		}
+	}
+}
+
+func TestViewportFoldsToolOutput(t *testing.T) {
+	vp := NewLogViewport(60, 10, nil)
+	vp.WriteLine("thinking about it")
+	vp.WriteToolHeader("| Bash     List files")
+	for i := range 100 {
+		vp.WriteLine(fmt.Sprintf("file%d.go", i))
+	}
+	vp.WriteNote("Loop 2 (Build Phase)")
+
+	view := ansi.Strip(vp.View())
+	if !strings.Contains(view, "▸ | Bash     List files  … 100 lines") || strings.Contains(view, "file5.go") {
+		t.Fatalf("tool output should be folded:\n%s", view)
+	}
+	if !strings.Contains(view, "Loop 2") || !strings.Contains(view, "thinking about it") {
+		t.Fatalf("lines around the block should stay visible:\n%s", view)
+	}
+
+	if !vp.ToggleBlock() {
+		t.Fatal("ToggleBlock found no block")
+	}
+	if view := ansi.Strip(vp.View()); !strings.Contains(view, "▾ | Bash") || !strings.Contains(view, "file0.go") {
+		t.Fatalf("block should be expanded:\n%s", view)
+	}
+
+	vp.CollapseAll(true)
+	vp.GotoBottom()
+	if view := ansi.Strip(vp.View()); strings.Contains(view, "file99.go") {
+		t.Fatalf("collapse all should fold the block again:\n%s", view)
+	}
+}
+
+func TestViewportKeepsProseAfterToolOutput(t *testing.T) {
+	vp := NewLogViewport(60, 10, nil)
+	vp.WriteToolHeader("| Read     prd.md")
+	vp.WriteLine("- [ ] Add login")
+	vp.WriteLine("- [ ] Add logout")
+	vp.WriteLine("")
+	vp.WriteLine("")
+	vp.WriteLine("I'll start with the login form.")
+
+	view := ansi.Strip(vp.View())
+	if strings.Contains(view, "Add logout") || !strings.Contains(view, "… 2 lines") {
+		t.Fatalf("tool output should be folded:\n%s", view)
+	}
+	if !strings.Contains(view, "I'll start with the login form.") {
+		t.Fatalf("the agent's reasoning after the blank lines should stay visible:\n%s", view)
+	}
+}
+
+func TestViewportFoldsListingsWithBlankLines(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "a/x.go", "a/y.go", "b/z.go", "b/c/w.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("ls", "-R")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Skip("ls -R:", err)
+	}
+	listing := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+
+	vp := NewLogViewport(60, 40, nil)
+	vp.WriteToolHeader("| Bash     ls -R")
+	for _, line := range listing {
+		vp.WriteLine(line)
+	}
+	vp.WriteNote("🔁 Loop 2 (BUILD Phase)")
+
+	view := ansi.Strip(vp.View())
+	for _, line := range []string{"./a:", "x.go", "./b:", "z.go", "./b/c:", "w.go"} {
+		if strings.Contains(view, line) {
+			t.Errorf("%q should be folded:\n%s", line, view)
+		}
+	}
+	if want := fmt.Sprintf("… %d lines", len(listing)); !strings.Contains(view, want) || !strings.Contains(view, "Loop 2") {
+		t.Errorf("the whole listing should fold into one block, want %q:\n%s", want, view)
+	}
+}
@@ -0,0 +1,329 @@
+// Package webhook posts run events as JSON to the URLs a team configures,
+// for chat and dashboards. Deliveries are made in the background and
+// retried with backoff; those that still fail are kept in a spool file in
+// the project and tried once more when the next run starts.
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"vibepup-tui/internal/atomicfile"
+	"vibepup-tui/notify"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the body, keyed with the
+// secret, as "sha256=<hex>".
+const SignatureHeader = "X-Vibepup-Signature"
+
+// EventHeader carries the event type.
+const EventHeader = "X-Vibepup-Event"
+
+// maxSpool is how many failed deliveries the spool keeps; older ones are
+// dropped.
+const maxSpool = 1000
+
+// SpoolPath is where deliveries that failed are kept.
+func SpoolPath(dir string) string {
+	return filepath.Join(dir, ".ralph", "webhook-spool.jsonl")
+}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	Event string `json:"event"` // a notify.Kind, e.g. "task_done"
+	// Text is the event in words, for chat.
+	Text      string `json:"text"`
+	Project   string `json:"project"`
+	Iteration int    `json:"iteration,omitempty"`
+	Model     string `json:"model,omitempty"`
+	// Task is the task done, or the one being worked on.
+	Task string `json:"task,omitempty"`
+	// Detail is the iteration's phase, the watchdog's reason, the line
+	// that looked like a prompt or the run's outcome.
+	Detail     string    `json:"detail,omitempty"`
+	Time       time.Time `json:"time"`
+	RunStarted time.Time `json:"run_started,omitzero"`
+}
+
+// NewPayload describes ev.
+func NewPayload(ev notify.Event) Payload {
+	text := ev.Title()
+	if msg := ev.Message(); msg != "" {
+		text += ": " + msg
+	}
+	return Payload{Event: string(ev.Kind), Text: text, Project: ev.Project, Iteration: ev.Iteration,
+		Model: ev.Model, Task: ev.Task, Detail: ev.Detail, Time: ev.Time, RunStarted: ev.Started}
+}
+
+// delivery is one payload for one URL. Failed ones are spooled as is.
+type delivery struct {
+	URL      string          `json:"url"`
+	Event    string          `json:"event"`
+	Body     json.RawMessage `json:"body"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error,omitempty"`
+	Failed   time.Time       `json:"failed,omitzero"`
+	// Rejected deliveries got a client error. They stay in the spool for
+	// a look but aren't tried again.
+	Rejected bool `json:"rejected,omitempty"`
+}
+
+// Sender delivers events to URLs. Set the fields, then Start it.
+type Sender struct {
+	URLs []string
+	// Events are the kinds to deliver; others are skipped.
+	Events []notify.Kind
+	// Secret signs each body in the SignatureHeader; empty sends none.
+	Secret string
+	// Retries is how many times a delivery is retried, waiting Backoff
+	// before the first retry and twice as long before each next.
+	Retries int
+	Backoff time.Duration
+	// Spool is the file failed deliveries are kept in.
+	Spool  string
+	Client *http.Client
+
+	queue  chan delivery
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex // guards the spool file
+}
+
+// Start begins delivering. The deliveries left in the spool are tried again
+// alongside, so they don't hold up the new ones.
+func (s *Sender) Start() {
+	if s.Client == nil {
+		s.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	s.queue = make(chan delivery, 256)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); s.run() }()
+	go func() { defer wg.Done(); s.drain() }()
+	go func() { wg.Wait(); close(s.done) }()
+}
+
+// Send queues ev for each URL if its kind is one of Events. It doesn't
+// wait; a full queue goes straight to the spool.
+func (s *Sender) Send(evs ...notify.Event) {
+	for _, ev := range evs {
+		if !s.wants(ev.Kind) {
+			continue
+		}
+		body, err := json.Marshal(NewPayload(ev))
+		if err != nil {
+			continue
+		}
+		for _, url := range s.URLs {
+			d := delivery{URL: url, Event: string(ev.Kind), Body: body}
+			select {
+			case s.queue <- d:
+			default:
+				d.Error, d.Failed = "the delivery queue was full", time.Now()
+				s.spool([]delivery{d})
+			}
+		}
+	}
+}
+
+func (s *Sender) wants(kind notify.Kind) bool {
+	for _, k := range s.Events {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Close waits up to timeout for the queued deliveries, then spools what's
+// left. The Sender can't be used after.
+func (s *Sender) Close(timeout time.Duration) {
+	close(s.queue)
+	select {
+	case <-s.done:
+	case <-time.After(timeout):
+		s.cancel()
+		<-s.done
+	}
+	s.cancel()
+}
+
+func (s *Sender) run() {
+	for d := range s.queue {
+		s.deliver(d)
+	}
+}
+
+// drain tries each spooled delivery once more, spooling it again if it
+// fails. What's left when the Sender closes is spooled untried.
+func (s *Sender) drain() {
+	ds := s.unspool()
+	var failed []delivery
+	for i, d := range ds {
+		if s.ctx.Err() != nil {
+			failed = append(failed, ds[i:]...)
+			break
+		}
+		d.Attempts++
+		if err := s.post(d); err != nil {
+			d.Error, d.Failed, d.Rejected = err.Error(), time.Now(), permanent(err)
+			failed = append(failed, d)
+		}
+	}
+	if len(failed) > 0 {
+		s.spool(failed)
+	}
+}
+
+// deliver posts d, retrying with backoff, and spools it if it still
+// fails.
+func (s *Sender) deliver(d delivery) {
+	wait := s.Backoff
+	for try := 0; ; try++ {
+		d.Attempts++
+		err := s.post(d)
+		if err == nil {
+			return
+		}
+		d.Error, d.Failed, d.Rejected = err.Error(), time.Now(), permanent(err)
+		if try == s.Retries || s.ctx.Err() != nil || d.Rejected {
+			break
+		}
+		select {
+		case <-time.After(wait):
+		case <-s.ctx.Done():
+		}
+		wait *= 2
+	}
+	s.spool([]delivery{d})
+}
+
+// statusError is a response other than 2xx.
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("the server answered %d %s", e.code, http.StatusText(e.code))
+}
+
+// permanent reports whether retrying err won't help: a client error other
+// than a timeout or rate limit.
+func permanent(err error) bool {
+	se, ok := err.(statusError)
+	return ok && se.code >= 400 && se.code < 500 && se.code != http.StatusRequestTimeout && se.code != http.StatusTooManyRequests
+}
+
+func (s *Sender) post(d delivery) error {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vibepup-tui")
+	req.Header.Set(EventHeader, d.Event)
+	if s.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.Secret, d.Body))
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError{resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// spool appends ds to the spool file, dropping the oldest entries past
+// maxSpool.
+func (s *Sender) spool(ds []delivery) {
+	if s.Spool == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := append(readSpool(s.Spool), ds...)
+	_ = writeSpool(s.Spool, kept[max(0, len(kept)-maxSpool):])
+}
+
+func writeSpool(path string, ds []delivery) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, d := range ds {
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return atomicfile.Write(path, buf.Bytes())
+}
+
+// unspool takes the deliveries to try again out of the spool file,
+// leaving the rejected ones.
+func (s *Sender) unspool() []delivery {
+	if s.Spool == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var retry, rejected []delivery
+	for _, d := range readSpool(s.Spool) {
+		if d.Rejected {
+			rejected = append(rejected, d)
+		} else {
+			retry = append(retry, d)
+		}
+	}
+	if len(retry) == 0 {
+		return nil
+	}
+	if len(rejected) == 0 {
+		os.Remove(s.Spool)
+	} else {
+		_ = writeSpool(s.Spool, rejected)
+	}
+	return retry
+}
+
+// readSpool reads a spool file, skipping lines it can't parse.
+func readSpool(path string) []delivery {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var out []delivery
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var d delivery
+		if json.Unmarshal(sc.Bytes(), &d) == nil && d.URL != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
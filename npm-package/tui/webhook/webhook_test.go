@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"vibepup-tui/notify"
+)
+
+// recorder is a webhook endpoint that answers with the codes in fail, in
+// turn, then 200.
+type recorder struct {
+	mu       sync.Mutex
+	fail     []int
+	attempts int
+	got      []*http.Request
+	bodies   [][]byte
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if len(r.fail) > 0 {
+		code := r.fail[0]
+		r.fail = r.fail[1:]
+		w.WriteHeader(code)
+		return
+	}
+	r.got, r.bodies = append(r.got, req), append(r.bodies, body)
+}
+
+func TestSenderRetriesAndSigns(t *testing.T) {
+	rec := &recorder{fail: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	spool := filepath.Join(t.TempDir(), "spool.jsonl")
+	s := &Sender{URLs: []string{srv.URL}, Events: []notify.Kind{notify.TaskDone}, Secret: "hush",
+		Retries: 3, Backoff: time.Millisecond, Spool: spool}
+	s.Start()
+	started := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	at := started.Add(5 * time.Minute)
+	s.Send(
+		notify.Event{Kind: notify.Iteration, Project: "/work/app", Iteration: 2, Detail: "BUILD", Time: at},
+		notify.Event{Kind: notify.TaskDone, Project: "/work/app", Iteration: 2, Model: "openai/gpt-5.2", Task: "Add login", Time: at, Started: started},
+	)
+	s.Close(5 * time.Second)
+
+	if rec.attempts != 3 || len(rec.got) != 1 {
+		t.Fatalf("got %d attempts and %d deliveries, want 3 and 1", rec.attempts, len(rec.got))
+	}
+	req, body := rec.got[0], rec.bodies[0]
+	if req.Header.Get(EventHeader) != "task_done" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", req.Header)
+	}
+	if sig := req.Header.Get(SignatureHeader); sig != Sign("hush", body) || len(sig) != len("sha256=")+64 {
+		t.Errorf("signature %q doesn't match the body", sig)
+	}
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatal(err)
+	}
+	want := Payload{Event: "task_done", Text: "Task done: Add login", Project: "/work/app", Iteration: 2,
+		Model: "openai/gpt-5.2", Task: "Add login", Time: at, RunStarted: started}
+	if p != want {
+		t.Errorf("payload = %+v\nwant %+v", p, want)
+	}
+	if ds := readSpool(spool); len(ds) != 0 {
+		t.Errorf("nothing should be spooled, got %+v", ds)
+	}
+}
+
+func TestSenderSpoolsFailures(t *testing.T) {
+	flaky := &recorder{fail: []int{500, 500, 500}}
+	down := httptest.NewServer(flaky)
+	defer down.Close()
+	rejecting := httptest.NewServer(&recorder{fail: []int{http.StatusNotFound}})
+	defer rejecting.Close()
+
+	spool := filepath.Join(t.TempDir(), ".ralph", "webhook-spool.jsonl")
+	s := &Sender{URLs: []string{down.URL, rejecting.URL}, Events: []notify.Kind{notify.Exhausted},
+		Retries: 2, Backoff: time.Millisecond, Spool: spool}
+	s.Start()
+	s.Send(notify.Event{Kind: notify.Exhausted, Project: "/work/app", Iteration: 4})
+	s.Close(5 * time.Second)
+
+	ds := readSpool(spool)
+	if len(ds) != 2 {
+		t.Fatalf("spooled %+v, want both deliveries", ds)
+	}
+	if ds[0].URL != down.URL || ds[0].Attempts != 3 || ds[0].Rejected || ds[0].Error == "" {
+		t.Errorf("the failing delivery should be retried twice and spooled: %+v", ds[0])
+	}
+	if ds[1].URL != rejecting.URL || ds[1].Attempts != 1 || !ds[1].Rejected {
+		t.Errorf("a 404 shouldn't be retried: %+v", ds[1])
+	}
+
+	// The next run delivers what's spooled first; the rejected one stays.
+	s = &Sender{Retries: 0, Spool: spool}
+	s.Start()
+	s.Close(5 * time.Second)
+	if len(flaky.got) != 1 {
+		t.Fatalf("the spooled delivery wasn't sent again: %d", len(flaky.got))
+	}
+	var p Payload
+	if err := json.Unmarshal(flaky.bodies[0], &p); err != nil || p.Event != "exhausted" || p.Iteration != 4 {
+		t.Errorf("redelivered %s, %v", flaky.bodies[0], err)
+	}
+	if ds := readSpool(spool); len(ds) != 1 || !ds[0].Rejected {
+		t.Errorf("only the rejected delivery should be left, got %+v", ds)
+	}
+}
+
+func TestSpoolDoesNotHoldUpNewDeliveries(t *testing.T) {
+	block := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer slow.Close()
+	live := &recorder{}
+	fast := httptest.NewServer(live)
+	defer fast.Close()
+
+	spool := filepath.Join(t.TempDir(), "spool.jsonl")
+	if err := writeSpool(spool, []delivery{{URL: slow.URL, Event: "finished", Body: []byte(`{}`), Attempts: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Sender{URLs: []string{fast.URL}, Events: []notify.Kind{notify.Started}, Retries: 5, Backoff: time.Second, Spool: spool}
+	s.Start()
+	s.Send(notify.Event{Kind: notify.Started})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		live.mu.Lock()
+		n := len(live.got)
+		live.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the new delivery waited for the spooled one")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(block)
+	s.Close(5 * time.Second)
+	if ds := readSpool(spool); len(ds) != 0 {
+		t.Errorf("the spooled delivery went through, yet %+v is left", ds)
+	}
+}
+
+func TestSpooledDeliveriesAreTriedOnce(t *testing.T) {
+	down := &recorder{fail: []int{500, 500, 500}}
+	srv := httptest.NewServer(down)
+	defer srv.Close()
+
+	spool := filepath.Join(t.TempDir(), "spool.jsonl")
+	if err := writeSpool(spool, []delivery{{URL: srv.URL, Event: "finished", Body: []byte(`{}`), Attempts: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Sender{Retries: 5, Backoff: time.Millisecond, Spool: spool}
+	s.Start()
+	s.Close(5 * time.Second)
+	if down.attempts != 1 {
+		t.Errorf("a spooled delivery should be tried once per run, got %d attempts", down.attempts)
+	}
+	if ds := readSpool(spool); len(ds) != 1 || ds[0].Attempts != 4 {
+		t.Errorf("it should be spooled again, got %+v", ds)
+	}
+}
+
+func TestCloseGivesUp(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	spool := filepath.Join(t.TempDir(), "spool.jsonl")
+	s := &Sender{URLs: []string{srv.URL}, Events: []notify.Kind{notify.Finished}, Retries: 5, Backoff: time.Second, Spool: spool}
+	s.Start()
+	s.Send(notify.Event{Kind: notify.Finished, Detail: "complete"}, notify.Event{Kind: notify.Finished, Detail: "stopped"})
+	start := time.Now()
+	s.Close(50 * time.Millisecond)
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Close took %v", time.Since(start))
+	}
+	if ds := readSpool(spool); len(ds) != 2 || ds[0].Attempts != 1 {
+		t.Errorf("undelivered events should be spooled without retrying, got %+v", ds)
+	}
+}